@@ -4,7 +4,13 @@ Azure Cloud Costs CMD
 package cmd
 
 import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
 	"github.com/charmbracelet/lipgloss"
+	"github.com/muandane/cloudcost/utils"
 	"github.com/spf13/cobra"
 )
 
@@ -22,6 +28,13 @@ var currency string
 var period int
 var bandwidth float64
 var eventCount float64
+var columnSelection []string
+var offline bool
+var refreshCache bool
+var cacheTTL time.Duration
+var snapshotFile string
+var concurrency int
+var snapshot *utils.Snapshot
 var typeColors = Colors{
 	Spot:   lipgloss.AdaptiveColor{Light: "#D83F31", Dark: "#D83F31"},
 	Normal: lipgloss.AdaptiveColor{Light: "#116D6E", Dark: "#00DFA2"},
@@ -37,6 +50,104 @@ for searching and calculating the pricing of Azure resources`,
 }
 
 func init() {
+	azureCmd.PersistentFlags().BoolVar(&offline, "offline", false, "Serve prices from the local cache only, whatever their age")
+	azureCmd.PersistentFlags().BoolVar(&refreshCache, "refresh", false, "Fetch prices again instead of using the local cache")
+	azureCmd.PersistentFlags().DurationVar(&cacheTTL, "cache-ttl", utils.DefaultCacheTTL, "How long cached prices are used before being fetched again (0 disables the cache)")
+	azureCmd.PersistentFlags().StringVar(&snapshotFile, "snapshot", "", "Query a snapshot file written by 'azure snapshot pull' instead of the API")
+	azureCmd.PersistentFlags().IntVar(&concurrency, "concurrency", 4, "Number of requests sent to the API at the same time")
+	azureCmd.MarkFlagsMutuallyExclusive("offline", "refresh")
 	azureCmd.AddCommand(calculatorCmd)
 	azureCmd.AddCommand(searchCmd)
+	azureCmd.AddCommand(estimateCmd)
+	azureCmd.AddCommand(terraformCmd)
+	azureCmd.AddCommand(armCmd)
+	azureCmd.AddCommand(snapshotCmd)
+	azureCmd.AddCommand(diffCmd)
+	azureCmd.AddCommand(compareCmd)
+	azureCmd.AddCommand(compareRegionsCmd)
+	azureCmd.AddCommand(cheapestCmd)
+	azureCmd.AddCommand(reservationsCmd)
+	azureCmd.AddCommand(browseCmd)
+	azureCmd.AddCommand(vmCmd)
+	azureCmd.AddCommand(aksCmd)
+	azureCmd.AddCommand(storageCmd)
+}
+
+// newClient returns a Retail Prices client for --currency using the local
+// cache as set by --offline, --refresh and --cache-ttl.
+func newClient() *utils.Client {
+	client := utils.NewClient(currency)
+	client.Concurrency = concurrency
+	// Progress would interleave with rows streamed to the same terminal, so
+	// it is only shown when stdout is redirected or rendered as a table.
+	if format, _ := resolveOutputFormat(); isTerminal(os.Stderr) && (format == "table" || !stdoutIsTerminal()) {
+		client.Progress = printProgress
+	}
+	if cacheTTL <= 0 && !offline {
+		return client
+	}
+	dir, err := utils.DefaultCacheDir()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Warning: prices are not cached:", err)
+		return client
+	}
+	client.Cache = &utils.Cache{Dir: dir, TTL: cacheTTL, Offline: offline, Refresh: refreshCache}
+	return client
+}
+
+// printProgress shows the pages fetched by a query on stderr, erasing the
+// line once it is done.
+func printProgress(pages, items int, done bool) {
+	if done {
+		fmt.Fprint(os.Stderr, "\r\033[K")
+		return
+	}
+	fmt.Fprintf(os.Stderr, "\rFetched %d pages, %d prices...", pages, items)
+}
+
+// newSource returns the --snapshot file when given, or a client of the API.
+func newSource() (utils.Source, error) {
+	if snapshotFile == "" {
+		return newClient(), nil
+	}
+	if snapshot == nil {
+		s, err := utils.OpenSnapshot(snapshotFile)
+		if err != nil {
+			return nil, err
+		}
+		if currency != "" && !strings.EqualFold(currency, s.Header.Currency) {
+			return nil, fmt.Errorf("%s holds %s prices, not %s", snapshotFile, s.Header.Currency, currency)
+		}
+		snapshot = s
+	}
+	return snapshot, nil
+}
+
+// fetchItems lists the items matching query.
+func fetchItems(cmd *cobra.Command, query utils.Filter) ([]utils.Item, error) {
+	var items []utils.Item
+	err := eachItem(cmd, query, func(item utils.Item) error {
+		items = append(items, item)
+		return nil
+	})
+	return items, err
+}
+
+// eachItem streams the items matching query to fn. API errors, including a
+// partial result, are returned as lookup failures so that a truncated price
+// list never passes a budget.
+func eachItem(cmd *cobra.Command, query utils.Filter, fn func(utils.Item) error) error {
+	source, err := newSource()
+	if err != nil {
+		return err
+	}
+	var fnErr error
+	err = source.Each(cmd.Context(), query, func(item utils.Item) error {
+		fnErr = fn(item)
+		return fnErr
+	})
+	if err != nil && err != fnErr {
+		return lookupFailed(err)
+	}
+	return err
 }
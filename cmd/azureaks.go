@@ -0,0 +1,354 @@
+package cmd //Azure Kubernetes Service Estimate CMD
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muandane/cloudcost/utils"
+	"github.com/spf13/cobra"
+)
+
+var clusterFile string
+var nodePools []string
+var aksTier string
+var loadBalancerSku string
+var publicIPs int
+var egressGB float64
+var allocateBy string
+
+// aksCmd represents the aks command
+var aksCmd = &cobra.Command{
+	Use:   "aks",
+	Short: "Estimate the cost of AKS clusters.",
+	Long:  `Use the azure aks subcommands to price Azure Kubernetes Service clusters and allocate their cost.`,
+}
+
+var aksEstimateCmd = &cobra.Command{
+	Use:   "estimate",
+	Short: "Estimate the monthly cost of an AKS cluster.",
+	Long: `Use the azure aks estimate subcommand to price the control plane, node pools, OS disks, load
+balancer, public IPs and egress of an AKS cluster, described in a YAML or JSON file or with
+flags, which override the file. Node pools with minCount and maxCount are priced at both
+bounds and the cost is reported as a range. Example file:
+
+  currency: EUR
+  region: westeurope
+  tier: Standard
+  nodePools:
+    - name: system
+      vmSize: Standard_D4s_v5
+      count: 3
+    - name: batch
+      vmSize: Standard_D8s_v5
+      minCount: 0
+      maxCount: 10
+      spot: true
+      osDiskType: Ephemeral
+    - name: win
+      vmSize: Standard_D4s_v5
+      osType: Windows
+      count: 2
+      osDiskStorage: StandardSSD_LRS
+      osDiskSizeGB: 256
+  loadBalancer: Standard
+  publicIPs: 2
+  egressGB: 500`,
+	Example: `  cloudcost azure aks estimate -r westeurope --tier Standard --node-pool name=system,vmSize=Standard_D4s_v5,count=3 --node-pool name=apps,vmSize=Standard_D8s_v5,minCount=2,maxCount=8 --egress-gb 200`,
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := resolveOutputFormat()
+		if err != nil {
+			return err
+		}
+		if err := checkBudgetFlags(cmd); err != nil {
+			return err
+		}
+		cluster := &utils.AKSCluster{}
+		if clusterFile != "" {
+			if cluster, err = utils.LoadAKSCluster(clusterFile); err != nil {
+				return err
+			}
+		}
+		if err := applyClusterFlags(cmd, cluster); err != nil {
+			return err
+		}
+		if currency == "" {
+			currency = cluster.Currency
+		}
+		if region == "" {
+			region = cluster.Region
+		}
+		if region == "" {
+			return fmt.Errorf("set the region with --region or in the cluster file")
+		}
+		cluster.Region = region
+
+		source, err := newSource()
+		if err != nil {
+			return err
+		}
+		estimator := &utils.Estimator{Prices: source, Region: region}
+		components, err := cluster.Components()
+		if err != nil {
+			return err
+		}
+		lines, err := estimator.EstimateAKS(cmd.Context(), components)
+		if err != nil {
+			return lookupFailed(err)
+		}
+		report := aksReport(lines, cluster.Autoscaled())
+		report.Title = "AKS cost estimate"
+		if clusterFile != "" {
+			report.Title += " of " + clusterFile
+		}
+		return report.finish(cmd, format)
+	},
+}
+
+var aksAllocateCmd = &cobra.Command{
+	Use:   "allocate <kubectl.json>...",
+	Short: "Allocate the cost of cluster nodes to namespaces and workloads.",
+	Long: `Use the azure aks allocate subcommand to price the nodes of clusters dumped with
+'kubectl get nodes,pods -A -o json', read from files or from stdin with '-', and split the
+monthly cost of every node between the pods it runs, half by their share of its allocatable
+CPU and half by their share of its memory, from their requests. Nodes are priced as VMs of
+their node.kubernetes.io/instance-type label in their topology.kubernetes.io/region. The
+capacity left unrequested is reported as idle.`,
+	Example: `  kubectl get nodes,pods -A -o json > cluster.json
+  cloudcost azure aks allocate cluster.json --by workload`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := resolveOutputFormat()
+		if err != nil {
+			return err
+		}
+		if allocateBy != "namespace" && allocateBy != "workload" {
+			return fmt.Errorf("unknown --by %q, expected namespace or workload", allocateBy)
+		}
+		dump := &utils.KubeDump{}
+		for _, name := range args {
+			if err := decodeKubeDump(dump, name); err != nil {
+				return err
+			}
+		}
+		if len(dump.Nodes) == 0 {
+			return fmt.Errorf("no node found, dump them with 'kubectl get nodes,pods -A -o json'")
+		}
+
+		source, err := newSource()
+		if err != nil {
+			return err
+		}
+		estimator := &utils.Estimator{Prices: source, Region: region}
+		lines, err := estimator.EstimateNodes(cmd.Context(), dump.Nodes)
+		if err != nil {
+			return lookupFailed(err)
+		}
+		report := allocationReport(dump, lines)
+		report.Title = "Cost allocation of " + strings.Join(args, ", ")
+		return report.finish(cmd, format)
+	},
+}
+
+func init() {
+	aksEstimateCmd.Flags().StringVarP(&clusterFile, "file", "f", "", "YAML or JSON file describing the cluster")
+	aksEstimateCmd.Flags().StringArrayVar(&nodePools, "node-pool", nil, "Node pool as key=value pairs, repeatable (e.g., 'name=apps,vmSize=Standard_D8s_v5,minCount=2,maxCount=8,spot=true')")
+	aksEstimateCmd.Flags().StringVar(&aksTier, "tier", "", "Control plane tier: Free, Standard or Premium")
+	aksEstimateCmd.Flags().StringVar(&loadBalancerSku, "load-balancer", "", "Load balancer SKU: Standard, Basic or None")
+	aksEstimateCmd.Flags().IntVar(&publicIPs, "public-ips", 0, "Number of Standard public IPs")
+	aksEstimateCmd.Flags().Float64Var(&egressGB, "egress-gb", 0, "Monthly data sent to the internet in GB")
+	aksEstimateCmd.Flags().StringVarP(&region, "region", "r", "", "Region")
+	aksEstimateCmd.Flags().StringVarP(&currency, "currency", "c", "", "Price Currency (e.g., 'USD' or 'EUR')")
+	addOutputFlag(aksEstimateCmd)
+	addBudgetFlags(aksEstimateCmd, true)
+	aksAllocateCmd.Flags().StringVar(&allocateBy, "by", "namespace", "Allocate to each namespace or each workload")
+	aksAllocateCmd.Flags().StringVarP(&region, "region", "r", "", "Region of the nodes without a region label")
+	aksAllocateCmd.Flags().StringVarP(&currency, "currency", "c", "", "Price Currency (e.g., 'USD' or 'EUR')")
+	addOutputFlag(aksAllocateCmd)
+	aksCmd.AddCommand(aksEstimateCmd)
+	aksCmd.AddCommand(aksAllocateCmd)
+}
+
+// applyClusterFlags overrides cluster with the flags that are set. Node pools
+// given as flags replace those of the file with the same name.
+func applyClusterFlags(cmd *cobra.Command, cluster *utils.AKSCluster) error {
+	for _, s := range nodePools {
+		pool, err := utils.ParseAKSNodePool(s)
+		if err != nil {
+			return err
+		}
+		replaced := false
+		for i := range cluster.NodePools {
+			if cluster.NodePools[i].Name == pool.Name {
+				cluster.NodePools[i], replaced = pool, true
+			}
+		}
+		if !replaced {
+			cluster.NodePools = append(cluster.NodePools, pool)
+		}
+	}
+	if cmd.Flags().Changed("tier") {
+		cluster.Tier = aksTier
+	}
+	if cmd.Flags().Changed("load-balancer") {
+		cluster.LoadBalancer = loadBalancerSku
+	}
+	if cmd.Flags().Changed("public-ips") {
+		cluster.PublicIPs = publicIPs
+	}
+	if cmd.Flags().Changed("egress-gb") {
+		cluster.EgressGB = egressGB
+	}
+	return nil
+}
+
+var aksRangeKeys = []string{"name", "service", "sku", "region", "meterName", "unitOfMeasure", "retailPrice", "minQuantity", "maxQuantity", "minMonthlyCost", "maxMonthlyCost", "note"}
+var aksRangeHeaders = []string{"Name", "Service", "SKU", "Region", "Meter", "Unit of Measure", "Retail Price", "Min Quantity", "Max Quantity", "Min Monthly Cost", "Max Monthly Cost", "Note"}
+
+// aksReport lists the components like an estimate, or with their minimum and
+// maximum quantity and cost when node pools autoscale. The budget is checked
+// against the maximum.
+func aksReport(lines []utils.AKSLine, autoscaled bool) costReport {
+	if !autoscaled {
+		estimate := make([]utils.Line, len(lines))
+		for i, line := range lines {
+			estimate[i] = line.Line
+		}
+		return estimateReport(estimate)
+	}
+	report := costReport{Keys: aksRangeKeys, Headers: aksRangeHeaders, Color: func(values []any, col int) lipgloss.TerminalColor {
+		if values[len(values)-1] != "" {
+			return typeColors.Spot
+		}
+		if values[0] == "TOTAL" {
+			return typeColors.Normal
+		}
+		if values[7] != values[8] && (col == 7 || col == 9) {
+			return typeColors.Low
+		}
+		return nil
+	}}
+	var minMonthly float64
+	for _, line := range lines {
+		r := line.Resource
+		values := []any{r.Name, r.Service, r.SKU, r.Region, "", "", nil, line.Min, line.Max, nil, nil, ""}
+		if line.Item != nil {
+			values[2] = line.Item.ArmSkuName
+			if values[2] == "" {
+				values[2] = line.Item.SkuName
+			}
+			values[1], values[4], values[5], values[6] = line.Item.ServiceName, line.Item.MeterName, line.Item.UnitOfMeasure, line.Item.RetailPrice
+			values[9], values[10] = line.MinMonthly, line.Monthly
+			minMonthly += line.MinMonthly
+			report.Monthly += line.Monthly
+		}
+		if line.Err != nil {
+			values[11] = line.Err.Error()
+			report.Unpriced = append(report.Unpriced, r.Name)
+			fmt.Fprintf(os.Stderr, "Warning: %s not priced: %v\n", r.Name, line.Err)
+		}
+		report.Rows = append(report.Rows, values)
+	}
+	report.Rows = append(report.Rows, []any{"TOTAL", "", "", "", "", "", nil, nil, nil, minMonthly, report.Monthly, ""})
+	return report
+}
+
+// decodeKubeDump adds the kubectl output of the file name, or stdin for '-',
+// to dump.
+func decodeKubeDump(dump *utils.KubeDump, name string) error {
+	if name == "-" {
+		return dump.Decode(os.Stdin)
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := dump.Decode(f); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// allocationReport lists the cost of every namespace, or workload with
+// --by workload, then the idle capacity and the total. Nodes that could not
+// be priced are left out of the costs and reported on stderr.
+func allocationReport(dump *utils.KubeDump, lines map[string]utils.Line) costReport {
+	nodeCost := map[string]float64{}
+	var unpriced []string
+	for _, node := range dump.Nodes {
+		line := lines[node.Name]
+		if line.Err != nil {
+			unpriced = append(unpriced, node.Name)
+			fmt.Fprintf(os.Stderr, "Warning: node %s not priced: %v\n", node.Name, line.Err)
+			continue
+		}
+		nodeCost[node.Name] = line.Monthly
+	}
+	allocations, idle := dump.Allocate(nodeCost)
+	if allocateBy == "namespace" {
+		var namespaces []utils.Allocation
+		for _, a := range allocations {
+			if n := len(namespaces); n > 0 && namespaces[n-1].Namespace == a.Namespace {
+				namespaces[n-1].Pods += a.Pods
+				namespaces[n-1].CPU += a.CPU
+				namespaces[n-1].Memory += a.Memory
+				namespaces[n-1].Monthly += a.Monthly
+				continue
+			}
+			a.Workload = ""
+			namespaces = append(namespaces, a)
+		}
+		allocations = namespaces
+	}
+
+	keys := []string{"namespace", "workload", "pods", "cpuRequests", "memoryRequestsGiB", "monthlyCost", "sharePercent"}
+	headers := []string{"Namespace", "Workload", "Pods", "CPU Requests", "Memory Requests GiB", "Monthly Cost", "Share %"}
+	if allocateBy == "namespace" {
+		keys = slices.Delete(keys, 1, 2)
+		headers = slices.Delete(headers, 1, 2)
+	}
+	report := costReport{Keys: keys, Headers: headers, Unpriced: unpriced, Color: func(values []any, col int) lipgloss.TerminalColor {
+		switch values[0] {
+		case "(idle)":
+			return typeColors.Low
+		case "TOTAL":
+			return typeColors.Normal
+		}
+		return nil
+	}}
+	for _, a := range allocations {
+		report.Monthly += a.Monthly
+	}
+	report.Monthly += idle.Monthly
+	share := func(monthly float64) any {
+		if report.Monthly == 0 {
+			return nil
+		}
+		return monthly / report.Monthly * 100
+	}
+	row := func(namespace, workload string, pods any, a utils.Allocation) []any {
+		values := []any{namespace, workload, pods, a.CPU, a.Memory / (1 << 30), a.Monthly, share(a.Monthly)}
+		if allocateBy == "namespace" {
+			values = slices.Delete(values, 1, 2)
+		}
+		return values
+	}
+	var pods int
+	for _, a := range allocations {
+		report.Rows = append(report.Rows, row(a.Namespace, a.Workload, a.Pods, a))
+		pods += a.Pods
+	}
+	report.Rows = append(report.Rows, row("(idle)", "", nil, idle))
+	var capacity utils.Allocation
+	for _, node := range dump.Nodes {
+		capacity.CPU += node.CPU
+		capacity.Memory += node.Memory
+	}
+	capacity.Monthly = report.Monthly
+	report.Rows = append(report.Rows, row("TOTAL", "", pods, capacity))
+	return report
+}
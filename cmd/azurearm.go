@@ -0,0 +1,70 @@
+package cmd //Azure ARM Template Estimate CMD
+
+import (
+	"github.com/muandane/cloudcost/utils"
+	"github.com/spf13/cobra"
+)
+
+var parametersFile string
+var location string
+
+// armCmd represents the arm command
+var armCmd = &cobra.Command{
+	Use:   "arm <template.json>",
+	Short: "Estimate the monthly cost of an ARM template.",
+	Long: `Use the azure arm subcommand to price the resources of an ARM deployment template,
+hand written or compiled from Bicep with:
+
+  az bicep build --file main.bicep
+
+Parameters, variables and copy loops are resolved so that SKUs, regions and counts
+reflect the deployment. Nested deployments with an inline template, which is how Bicep
+compiles modules, are expanded; linked templates are not. Only the fields that set prices,
+such as the location, SKU and sizes, are evaluated. Resources that cannot be priced are
+listed with the reason.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := resolveOutputFormat()
+		if err != nil {
+			return err
+		}
+		if err := checkBudgetFlags(cmd); err != nil {
+			return err
+		}
+		template, err := utils.LoadTemplate(args[0])
+		if err != nil {
+			return err
+		}
+		var parameters map[string]any
+		if parametersFile != "" {
+			if parameters, err = utils.LoadParameters(parametersFile); err != nil {
+				return err
+			}
+		}
+		resources, err := utils.ArmResources(template, parameters, utils.ArmOptions{Location: location, StorageGB: storageGB})
+		if err != nil {
+			return err
+		}
+		source, err := newSource()
+		if err != nil {
+			return err
+		}
+		estimator := &utils.Estimator{Prices: source, Region: location}
+		lines, err := estimator.EstimateTemplate(cmd.Context(), resources)
+		if err != nil {
+			return lookupFailed(err)
+		}
+		report := estimateReport(lines)
+		report.Title = "Cost estimate of " + args[0]
+		return report.finish(cmd, format)
+	},
+}
+
+func init() {
+	armCmd.Flags().StringVarP(&parametersFile, "parameters", "p", "", "Deployment parameters file")
+	armCmd.Flags().StringVarP(&location, "location", "l", "", "Location of the target resource group, required by templates using resourceGroup().location")
+	armCmd.Flags().StringVarP(&currency, "currency", "c", "", "Price Currency (e.g., 'USD' or 'EUR')")
+	armCmd.Flags().Float64Var(&storageGB, "storage-gb", 100, "Capacity in GB assumed for each storage account")
+	addOutputFlag(armCmd)
+	addBudgetFlags(armCmd, true)
+}
@@ -0,0 +1,657 @@
+package cmd //Azure Price Browser CMD
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muandane/cloudcost/utils"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var exportFile string
+
+// browseCmd represents the browse command
+var browseCmd = &cobra.Command{
+	Use:   "browse",
+	Short: "Browse Azure prices interactively.",
+	Long: `Use the azure browse subcommand to explore prices in the terminal: pick a region and drill
+into service families, services and products down to their prices, and add them to a basket
+totalling their monthly cost that can be exported as a file for 'azure estimate'. The prices
+of a service family are fetched when it is opened.
+
+Keys: enter or → opens a row, esc or ← goes back, / filters as you type, s sorts by the next
+column and S reverses the sort, t switches the price type, a adds a price to the basket, b
+shows the basket where + and - change the quantity and d removes a line, e exports the
+basket and q quits.`,
+	Example: `  cloudcost azure browse --region westeurope --export basket.yaml`,
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !isTerminal(os.Stdin) || !stdoutIsTerminal() {
+			return errors.New("browse needs an interactive terminal")
+		}
+		source, err := newSource()
+		if err != nil {
+			return err
+		}
+		// Adaptive colors query the terminal background, which has to be
+		// done before Bubble Tea starts reading the input.
+		lipgloss.HasDarkBackground()
+		m := newBrowseModel(cmd.Context(), source)
+		if region != "" {
+			m.openRegion(utils.NormalizeRegion(region))
+		}
+		p := tea.NewProgram(m, tea.WithAltScreen(), tea.WithContext(cmd.Context()))
+		if client, ok := source.(*utils.Client); ok {
+			client.Progress = func(pages, items int, done bool) {
+				p.Send(progressMsg{pages: pages, items: items})
+			}
+		}
+		_, err = p.Run()
+		return err
+	},
+}
+
+func init() {
+	browseCmd.Flags().StringVarP(&region, "region", "r", "", "Region to open (e.g., 'westeurope')")
+	browseCmd.Flags().StringVarP(&currency, "currency", "c", "", "Price Currency (e.g., 'USD' or 'EUR')")
+	browseCmd.Flags().StringVar(&exportFile, "export", "basket.yaml", "File the basket is exported to")
+}
+
+// The drill-down levels of the browser.
+const (
+	levelRegion = iota
+	levelFamily
+	levelService
+	levelProduct
+	levelPrice
+)
+
+// browsePriceTypes are the price types t switches between, "" showing all.
+var browsePriceTypes = []string{"", "Consumption", "Reservation", "DevTestConsumption"}
+
+// browseRow is a row of a page. Key is what the row opens.
+type browseRow struct {
+	key   string
+	cells []any
+	item  *utils.Item
+}
+
+// browsePage is the list shown at a drill-down level.
+type browsePage struct {
+	level     int
+	title     string
+	headers   []string
+	rows      []browseRow
+	filter    string
+	sortCol   int
+	sortDesc  bool
+	priceType int
+	cursor    int
+	offset    int
+}
+
+// visible returns the rows matching the filter and price type, sorted.
+func (p *browsePage) visible() []browseRow {
+	var rows []browseRow
+	for _, row := range p.rows {
+		if p.priceType > 0 && row.item != nil && row.item.Type != browsePriceTypes[p.priceType] {
+			continue
+		}
+		if fuzzyMatch(p.filter, row.cells) {
+			rows = append(rows, row)
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, b := rows[i].cells[p.sortCol], rows[j].cells[p.sortCol]
+		if p.sortDesc {
+			a, b = b, a
+		}
+		return lessCell(a, b)
+	})
+	return rows
+}
+
+// fuzzyMatch reports whether the letters of filter appear in order in the
+// cells, ignoring case.
+func fuzzyMatch(filter string, cells []any) bool {
+	text := make([]string, len(cells))
+	for i, cell := range cells {
+		text[i] = formatCell(cell, "")
+	}
+	remaining := []rune(strings.ToLower(filter))
+	for _, r := range strings.ToLower(strings.Join(text, " ")) {
+		if len(remaining) > 0 && r == remaining[0] {
+			remaining = remaining[1:]
+		}
+	}
+	return len(remaining) == 0
+}
+
+// basketLine is a price added to the basket.
+type basketLine struct {
+	item     utils.Item
+	quantity int
+}
+
+// monthly returns the monthly cost of the line, false for prices that are
+// not billed by time.
+func (l basketLine) monthly() (float64, bool) {
+	cost, ok := utils.MonthlyCost(l.item)
+	return cost * float64(l.quantity), ok
+}
+
+type progressMsg struct {
+	pages, items int
+}
+
+type pricesLoadedMsg struct {
+	region, family string
+	items          []utils.Item
+	err            error
+}
+
+type browseModel struct {
+	ctx    context.Context
+	source utils.Source
+	region string
+	family string
+	// loaded holds the prices of the service families opened so far, by
+	// region and family.
+	loaded    map[string][]utils.Item
+	pages     []*browsePage
+	filtering bool
+	loading   string
+	progress  progressMsg
+
+	basket       []basketLine
+	showBasket   bool
+	basketCursor int
+
+	status string
+	width  int
+	height int
+}
+
+func newBrowseModel(ctx context.Context, source utils.Source) *browseModel {
+	m := &browseModel{ctx: ctx, source: source, loaded: map[string][]utils.Item{}}
+	page := &browsePage{level: levelRegion, title: "Regions", headers: []string{"Region", "Name", "Geography"}}
+	for _, r := range utils.Regions {
+		page.rows = append(page.rows, browseRow{key: r.Name, cells: []any{r.Name, r.DisplayName, r.Geography}})
+	}
+	m.pages = []*browsePage{page}
+	return m
+}
+
+func (m *browseModel) page() *browsePage {
+	return m.pages[len(m.pages)-1]
+}
+
+func (m *browseModel) push(page *browsePage) {
+	m.pages = append(m.pages, page)
+	m.status = ""
+}
+
+// openRegion shows the service families of a region.
+func (m *browseModel) openRegion(name string) {
+	m.region = name
+	for i, row := range m.page().visible() {
+		if row.key == name {
+			m.page().cursor = i
+		}
+	}
+	page := &browsePage{level: levelFamily, title: name, headers: []string{"Service Family"}}
+	for _, family := range utils.ServiceFamilies {
+		page.rows = append(page.rows, browseRow{key: family, cells: []any{family}})
+	}
+	m.push(page)
+}
+
+// loadFamily fetches the prices of a service family in the region.
+func (m *browseModel) loadFamily(family string) tea.Cmd {
+	region := m.region
+	if items, ok := m.loaded[region+"|"+family]; ok {
+		return func() tea.Msg {
+			return pricesLoadedMsg{region: region, family: family, items: items}
+		}
+	}
+	m.loading = fmt.Sprintf("Fetching %s prices in %s", family, region)
+	m.progress = progressMsg{}
+	return func() tea.Msg {
+		query := utils.And(utils.Eq("armRegionName", region), utils.Eq("serviceFamily", family))
+		items, err := utils.ListItems(m.ctx, m.source, query)
+		return pricesLoadedMsg{region: region, family: family, items: items, err: err}
+	}
+}
+
+// groupPage lists the distinct values of field among items, with their
+// number of prices and the lowest monthly cost.
+func groupPage(level int, title, header string, items []utils.Item, field func(utils.Item) string) *browsePage {
+	page := &browsePage{level: level, title: title, headers: []string{header, "Prices", "From Monthly"}}
+	index := map[string]int{}
+	for _, item := range items {
+		name := field(item)
+		i, ok := index[name]
+		if !ok {
+			i = len(page.rows)
+			index[name] = i
+			page.rows = append(page.rows, browseRow{key: name, cells: []any{name, 0.0, nil}})
+		}
+		cells := page.rows[i].cells
+		cells[1] = cells[1].(float64) + 1
+		if monthly, ok := utils.MonthlyCost(item); ok && item.Type == "Consumption" {
+			if from, ok := cells[2].(float64); !ok || monthly < from {
+				cells[2] = monthly
+			}
+		}
+	}
+	return page
+}
+
+// pricePage lists the prices of a product.
+func (m *browseModel) pricePage(product string) *browsePage {
+	page := &browsePage{level: levelPrice, title: product, headers: []string{"SKU", "Meter", "Price Type", "Term", "Retail Price", "Unit of Measure", "Monthly Price", "Tier"}}
+	for _, item := range m.loaded[m.region+"|"+m.family] {
+		if item.ServiceName != m.page().title || item.ProductName != product {
+			continue
+		}
+		item := item
+		sku := item.ArmSkuName
+		if sku == "" {
+			sku = item.SkuName
+		}
+		page.rows = append(page.rows, browseRow{
+			key:   utils.PriceKey(item),
+			cells: []any{sku, item.MeterName, item.Type, item.ReservationTerm, item.RetailPrice, item.UnitOfMeasure, optional(monthlyPrice(item)), item.TierMinimumUnits},
+			item:  &item,
+		})
+	}
+	return page
+}
+
+func (m *browseModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m *browseModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+	case progressMsg:
+		m.progress = msg
+	case pricesLoadedMsg:
+		m.loading = ""
+		if msg.err != nil {
+			m.status = "Error: " + msg.err.Error()
+			return m, nil
+		}
+		m.loaded[msg.region+"|"+msg.family] = msg.items
+		m.family = msg.family
+		page := groupPage(levelService, msg.family, "Service", msg.items, func(item utils.Item) string { return item.ServiceName })
+		m.push(page)
+		if len(msg.items) == 0 {
+			m.status = fmt.Sprintf("No %s prices in %s", msg.family, msg.region)
+		}
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			return m, tea.Quit
+		}
+		if m.loading != "" {
+			return m, nil
+		}
+		if m.filtering {
+			m.updateFilter(msg)
+			return m, nil
+		}
+		if m.showBasket {
+			return m, m.updateBasket(msg)
+		}
+		return m, m.updateList(msg)
+	}
+	return m, nil
+}
+
+func (m *browseModel) updateFilter(msg tea.KeyMsg) {
+	page := m.page()
+	switch msg.Type {
+	case tea.KeyEnter:
+		m.filtering = false
+	case tea.KeyEsc:
+		m.filtering = false
+		page.filter = ""
+	case tea.KeyBackspace:
+		if r := []rune(page.filter); len(r) > 0 {
+			page.filter = string(r[:len(r)-1])
+		}
+	case tea.KeyRunes, tea.KeySpace:
+		page.filter += string(msg.Runes)
+	}
+	page.cursor, page.offset = 0, 0
+}
+
+func (m *browseModel) updateList(msg tea.KeyMsg) tea.Cmd {
+	page := m.page()
+	rows := page.visible()
+	switch msg.String() {
+	case "q":
+		return tea.Quit
+	case "up", "k":
+		page.cursor--
+	case "down", "j":
+		page.cursor++
+	case "pgup":
+		page.cursor -= m.listHeight()
+	case "pgdown":
+		page.cursor += m.listHeight()
+	case "home", "g":
+		page.cursor = 0
+	case "end", "G":
+		page.cursor = len(rows) - 1
+	case "/":
+		m.filtering = true
+	case "s":
+		page.sortCol = (page.sortCol + 1) % len(page.headers)
+		page.sortDesc = false
+	case "S":
+		page.sortDesc = !page.sortDesc
+	case "t":
+		if page.level == levelPrice {
+			page.priceType = (page.priceType + 1) % len(browsePriceTypes)
+			page.cursor = 0
+		}
+	case "b":
+		m.showBasket = true
+	case "esc", "left", "h", "backspace":
+		if page.filter != "" && msg.String() == "esc" {
+			page.filter = ""
+		} else if len(m.pages) > 1 {
+			m.pages = m.pages[:len(m.pages)-1]
+			m.status = ""
+		}
+	case "enter", "right", "l":
+		if page.cursor >= len(rows) {
+			return nil
+		}
+		return m.open(rows[page.cursor])
+	case "a", " ":
+		if page.cursor < len(rows) && rows[page.cursor].item != nil {
+			m.addToBasket(*rows[page.cursor].item)
+		}
+	}
+	page.cursor = max(0, min(page.cursor, len(page.visible())-1))
+	return nil
+}
+
+// open drills down into row.
+func (m *browseModel) open(row browseRow) tea.Cmd {
+	page := m.page()
+	items := m.loaded[m.region+"|"+m.family]
+	switch page.level {
+	case levelRegion:
+		m.openRegion(row.key)
+	case levelFamily:
+		return m.loadFamily(row.key)
+	case levelService:
+		var service []utils.Item
+		for _, item := range items {
+			if item.ServiceName == row.key {
+				service = append(service, item)
+			}
+		}
+		m.push(groupPage(levelProduct, row.key, "Product", service, func(item utils.Item) string { return item.ProductName }))
+	case levelProduct:
+		m.push(m.pricePage(row.key))
+	case levelPrice:
+		m.addToBasket(*row.item)
+	}
+	return nil
+}
+
+func (m *browseModel) addToBasket(item utils.Item) {
+	for i, line := range m.basket {
+		if utils.PriceKey(line.item) == utils.PriceKey(item) {
+			m.basket[i].quantity++
+			m.status = fmt.Sprintf("%s × %d in the basket", item.MeterName, m.basket[i].quantity)
+			return
+		}
+	}
+	m.basket = append(m.basket, basketLine{item: item, quantity: 1})
+	m.status = fmt.Sprintf("Added %s to the basket", item.MeterName)
+}
+
+func (m *browseModel) updateBasket(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "q":
+		return tea.Quit
+	case "b", "esc", "left", "h":
+		m.showBasket = false
+	case "up", "k":
+		m.basketCursor--
+	case "down", "j":
+		m.basketCursor++
+	case "+", "=":
+		if m.basketCursor < len(m.basket) {
+			m.basket[m.basketCursor].quantity++
+		}
+	case "-":
+		if m.basketCursor < len(m.basket) && m.basket[m.basketCursor].quantity > 1 {
+			m.basket[m.basketCursor].quantity--
+		}
+	case "d", "x", "delete":
+		if m.basketCursor < len(m.basket) {
+			m.basket = append(m.basket[:m.basketCursor], m.basket[m.basketCursor+1:]...)
+		}
+	case "e":
+		if err := m.exportBasket(); err != nil {
+			m.status = "Error: " + err.Error()
+		} else {
+			m.status = fmt.Sprintf("Exported %d resources to %s", len(m.basket), exportFile)
+		}
+	}
+	m.basketCursor = max(0, min(m.basketCursor, len(m.basket)-1))
+	return nil
+}
+
+// basketTotal sums the monthly cost of the basket lines billed by time.
+func (m *browseModel) basketTotal() float64 {
+	total := 0.0
+	for _, line := range m.basket {
+		if monthly, ok := line.monthly(); ok {
+			total += monthly
+		}
+	}
+	return total
+}
+
+// basketResource is a basket line as a resource of an estimate file.
+type basketResource struct {
+	Name           string  `yaml:"name"`
+	Service        string  `yaml:"service"`
+	SKU            string  `yaml:"sku,omitempty"`
+	Product        string  `yaml:"product,omitempty"`
+	Meter          string  `yaml:"meter,omitempty"`
+	ExcludeProduct string  `yaml:"excludeProduct,omitempty"`
+	Region         string  `yaml:"region,omitempty"`
+	PriceType      string  `yaml:"priceType,omitempty"`
+	Term           string  `yaml:"term,omitempty"`
+	Quantity       float64 `yaml:"quantity,omitempty"`
+}
+
+// exportBasket writes the basket to --export as a spec for azure estimate.
+func (m *browseModel) exportBasket() error {
+	if len(m.basket) == 0 {
+		return errors.New("the basket is empty")
+	}
+	spec := struct {
+		Currency  string           `yaml:"currency,omitempty"`
+		Resources []basketResource `yaml:"resources"`
+	}{Currency: m.basket[0].item.CurrencyCode}
+	names := map[string]int{}
+	for _, line := range m.basket {
+		item := line.item
+		r := basketResource{Service: item.ServiceName, SKU: item.ArmSkuName, Product: item.ProductName, Meter: item.MeterName, Region: item.ArmRegionName}
+		if r.SKU == "" {
+			r.SKU = item.SkuName
+		}
+		if utils.OSOf(item) == utils.Linux {
+			r.ExcludeProduct = utils.Windows
+		}
+		if item.Type != "Consumption" {
+			r.PriceType, r.Term = item.Type, item.ReservationTerm
+		}
+		if line.quantity > 1 {
+			r.Quantity = float64(line.quantity)
+		}
+		name := strings.ToLower(strings.Join(strings.Fields(r.SKU+" "+item.MeterName), "-"))
+		names[name]++
+		if names[name] > 1 {
+			name = fmt.Sprintf("%s-%d", name, names[name])
+		}
+		r.Name = name
+		spec.Resources = append(spec.Resources, r)
+	}
+	var b strings.Builder
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(spec); err != nil {
+		return err
+	}
+	return os.WriteFile(exportFile, []byte(b.String()), 0o644)
+}
+
+// listHeight is the number of rows that fit between the header and footer.
+func (m *browseModel) listHeight() int {
+	return max(1, m.height-6)
+}
+
+var (
+	browseTitleStyle    = lipgloss.NewStyle().Bold(true)
+	browseHeaderStyle   = lipgloss.NewStyle().Bold(true).Underline(true)
+	browseSelectedStyle = lipgloss.NewStyle().Bold(true).Foreground(typeColors.Normal)
+	browseHelpStyle     = lipgloss.NewStyle().Faint(true)
+)
+
+func (m *browseModel) View() string {
+	var b strings.Builder
+	crumbs := []string{"Azure prices"}
+	for _, page := range m.pages[1:] {
+		crumbs = append(crumbs, page.title)
+	}
+	title := strings.Join(crumbs, " › ")
+	if m.showBasket {
+		title += " › Basket"
+	}
+	fmt.Fprintf(&b, "%s    %s\n\n", browseTitleStyle.Render(title), fmt.Sprintf("Basket: %d, %s/month", len(m.basket), formatCell(m.basketTotal(), "")))
+
+	var headers []string
+	var rows [][]any
+	cursor, offset := 0, 0
+	if m.showBasket {
+		headers = []string{"SKU", "Meter", "Product", "Region", "Price Type", "Term", "Quantity", "Monthly Price"}
+		for _, line := range m.basket {
+			item := line.item
+			monthly, ok := line.monthly()
+			var cost any
+			if ok {
+				cost = monthly
+			}
+			rows = append(rows, []any{item.ArmSkuName, item.MeterName, item.ProductName, item.ArmRegionName, item.Type, item.ReservationTerm, line.quantity, cost})
+		}
+		cursor = m.basketCursor
+		offset = max(0, cursor-m.listHeight()+1)
+	} else {
+		page := m.page()
+		headers = append([]string{}, page.headers...)
+		mark := "▲"
+		if page.sortDesc {
+			mark = "▼"
+		}
+		headers[page.sortCol] += " " + mark
+		for _, row := range page.visible() {
+			rows = append(rows, row.cells)
+		}
+		if page.cursor < page.offset {
+			page.offset = page.cursor
+		} else if page.cursor >= page.offset+m.listHeight() {
+			page.offset = page.cursor - m.listHeight() + 1
+		}
+		cursor, offset = page.cursor, page.offset
+	}
+	b.WriteString(renderBrowseRows(headers, rows, cursor, offset, m.listHeight(), m.width))
+
+	switch {
+	case m.loading != "":
+		fmt.Fprintf(&b, "\n%s... %d pages, %d prices", m.loading, m.progress.pages, m.progress.items)
+	case m.filtering:
+		fmt.Fprintf(&b, "\n/%s█", m.page().filter)
+	case m.status != "":
+		b.WriteString("\n" + m.status)
+	case !m.showBasket && m.page().filter != "":
+		fmt.Fprintf(&b, "\nFilter: %s", m.page().filter)
+	case !m.showBasket && m.page().priceType > 0:
+		fmt.Fprintf(&b, "\nPrice type: %s", browsePriceTypes[m.page().priceType])
+	default:
+		b.WriteString("\n")
+	}
+	help := "enter open · esc back · / filter · s/S sort · t price type · a add · b basket · q quit"
+	if m.showBasket {
+		help = "+/- quantity · d remove · e export to " + exportFile + " · b back · q quit"
+	}
+	b.WriteString("\n" + browseHelpStyle.Render(help))
+	return b.String()
+}
+
+// renderBrowseRows lays out the rows in view as padded columns, the row at
+// cursor highlighted.
+func renderBrowseRows(headers []string, rows [][]any, cursor, offset, height, width int) string {
+	end := min(len(rows), offset+height)
+	widths := make([]int, len(headers))
+	for i, h := range headers {
+		widths[i] = lipgloss.Width(h)
+	}
+	cells := make([][]string, 0, end-offset)
+	for _, row := range rows[offset:end] {
+		line := make([]string, len(row))
+		for i, cell := range row {
+			line[i] = formatCell(cell, "---")
+			if r := []rune(line[i]); len(r) > 40 {
+				line[i] = string(r[:39]) + "…"
+			}
+			widths[i] = max(widths[i], lipgloss.Width(line[i]))
+		}
+		cells = append(cells, line)
+	}
+	pad := func(values []string) string {
+		parts := make([]string, len(values))
+		for i, v := range values {
+			parts[i] = v + strings.Repeat(" ", widths[i]-lipgloss.Width(v))
+		}
+		line := strings.Join(parts, "  ")
+		if width > 0 && lipgloss.Width(line) > width {
+			line = string([]rune(line)[:width])
+		}
+		return line
+	}
+
+	var b strings.Builder
+	b.WriteString("  " + browseHeaderStyle.Render(pad(headers)) + "\n")
+	for i, line := range cells {
+		text := pad(line)
+		if offset+i == cursor {
+			text = browseSelectedStyle.Render("> " + text)
+		} else {
+			text = "  " + text
+		}
+		b.WriteString(text + "\n")
+	}
+	for i := len(cells); i < height; i++ {
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -1,13 +1,6 @@
 package cmd //Azure Price Calculator CMD
 
 import (
-	"fmt"
-	"net/url"
-	"os"
-	"strings"
-
-	"github.com/charmbracelet/lipgloss"
-	"github.com/charmbracelet/lipgloss/table"
 	"github.com/muandane/cloudcost/utils"
 	"github.com/spf13/cobra"
 )
@@ -17,82 +10,50 @@ var calculatorCmd = &cobra.Command{
 	Use:   "calculator",
 	Short: "Calculate Azure resource pricing based on parameters.",
 	Long: `Use the azure calculator subcommand to calculate the pricing of an Azure resource.
-You can specify the resource name and additional parameters to get accurate pricing details.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		re := lipgloss.NewRenderer(os.Stdout)
-		baseStyle := re.NewStyle().Padding(0, 1)
-		headerStyle := baseStyle.Copy().Foreground(lipgloss.AdaptiveColor{Light: "#186F65", Dark: "#1AACAC"}).Bold(true)
-
-		tableData := [][]string{{"SKU", "Retail Price", "Unit of Measure", "Monthly Price", "Usage", "Region", "Product Name"}}
-		apiURL := "https://prices.azure.com/api/retail/prices?"
-		currencyType := fmt.Sprintf("currencyCode='%s'", currency)
-		query := utils.Query(region, service, vmType, pricingType)
-		for {
-			var resp utils.Response
-			err := utils.GetJSON(apiURL+currencyType+"&$filter="+url.QueryEscape(query), &resp)
-			if err != nil {
-				fmt.Println("Error:", err)
-				return
-			}
-
-			for _, item := range resp.Items {
-				var usage float64
-				if strings.Contains(item.UnitOfMeasure, "GB") {
-					usage = calculateUsageGB(bandwidth, period, item.RetailPrice) // Assuming a bandwidth of 1 GB/day and a span of 30 days
-				} else if strings.Contains(item.UnitOfMeasure, "Hour") {
-					usage = calculateUsageHourly(bandwidth, period, item.RetailPrice) // Assuming a bandwidth of 1 GB/day and
-				} else if strings.Contains(item.UnitOfMeasure, "Month") {
-					usage = calculateUsageMonthly(bandwidth, period, item.RetailPrice) // Assuming a bandwidth of 1 GB/day and
-				} else if strings.Contains(item.UnitOfMeasure, "M") {
-					usage = calculateUsageEvents(eventCount, item.RetailPrice)
-				}
+You can specify the resource name and additional parameters to get accurate pricing details.
+Tiered meters are billed across their tiers, shown as a row each with the quantity it bills.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := resolveOutputFormat()
+		if err != nil {
+			return err
+		}
+		cols, err := selectColumns(cmd, hybridColumns(calculatorColumns))
+		if err != nil {
+			return err
+		}
+		query, err := buildFilter(cmd)
+		if err != nil {
+			return err
+		}
 
-				var monthlyPrice string
-				if pricingType != "Reservation" && !strings.Contains(item.UnitOfMeasure, "GB") && !strings.Contains(item.UnitOfMeasure, "Month") && !strings.Contains(item.UnitOfMeasure, "M") && !strings.Contains(item.UnitOfMeasure, "K") {
-					monthlyPrice = fmt.Sprintf("%v", item.RetailPrice*730) // Calculate the monthly price
-				} else {
-					monthlyPrice = "---"
-				}
-				tableData = append(tableData, []string{item.ArmSkuName, fmt.Sprintf("%f", item.RetailPrice), item.UnitOfMeasure, fmt.Sprintf("%v", monthlyPrice), fmt.Sprintf("%f", usage), item.ArmRegionName, item.MeterName, item.ProductName})
+		out, err := newPriceWriter(format, cols)
+		if err != nil {
+			return err
+		}
+		// Tiered meters come as a row per tier, in any order, grouped to
+		// bill the usage across them.
+		var meters [][]priceRow
+		index := map[string]int{}
+		err = eachPriceRow(cmd, query, func(r priceRow) error {
+			key := utils.MeterKey(r.Item)
+			if i, ok := index[key]; ok {
+				meters[i] = append(meters[i], r)
+				return nil
 			}
-			if resp.NextPageLink == "" {
+			index[key] = len(meters)
+			meters = append(meters, []priceRow{r})
+			return nil
+		})
+		for _, rows := range meters {
+			if err != nil {
 				break
 			}
-			apiURL = resp.NextPageLink
+			err = writeCalculatorRows(out, cols, rows)
 		}
-		headers := []string{"SKU", "Retail Price", "Unit of Measure", "Monthly Price", "Usage", "Region", "Meter Name", "Product Name"}
-		CapitalizeHeaders := func(tableData []string) []string {
-			for i := range tableData {
-				tableData[i] = strings.ToUpper(tableData[i])
-			}
-			return tableData
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
 		}
-		t := table.New().
-			Border(lipgloss.NormalBorder()).
-			BorderStyle(re.NewStyle().Foreground(lipgloss.AdaptiveColor{Light: "#186F65", Dark: "#1AACAC"})).
-			Headers(CapitalizeHeaders(headers)...).
-			Width(120).
-			Rows(tableData[1:]...). // Pass only the rows to the Rows function
-			StyleFunc(func(row, col int) lipgloss.Style {
-				if row == 0 {
-					return headerStyle
-				}
-				if col == 4 {
-					// Check if the "Meter" column contains "Spot" or "Low"
-					meter := tableData[row-0][4]                                       // The "Meter" column is the 5th column (index 4)
-					color := lipgloss.AdaptiveColor{Light: "#186F65", Dark: "#1AACAC"} // Default color
-					if strings.Contains(meter, "Spot") {
-						color = typeColors.Spot
-					} else if strings.Contains(meter, "Low") {
-						color = typeColors.Low
-					} else {
-						color = typeColors.Normal
-					}
-					return baseStyle.Copy().Foreground(color)
-				}
-				return baseStyle.Copy().Foreground(lipgloss.AdaptiveColor{Light: "#053B50", Dark: "#F1EFEF"})
-			})
-		fmt.Println(t)
+		return err
 	},
 }
 
@@ -102,26 +63,83 @@ func init() {
 	calculatorCmd.Flags().StringVarP(&service, "service", "s", "", "Azure service (e.g., 'D' for D series vms, Private for Private links)")
 	calculatorCmd.Flags().StringVarP(&pricingType, "pricing-type", "p", "Consumption", "Pricing Type (e.g., 'Consumption' or 'Reservation')")
 	calculatorCmd.Flags().StringVarP(&currency, "currency", "c", "", "Price Currency (e.g., 'USD' or 'EUR')")
-	calculatorCmd.Flags().Float64VarP(&bandwidth, "bandwidth", "b", 1, "Pricing Type (e.g., 'Consumption' or 'Reservation')")
+	calculatorCmd.Flags().Float64VarP(&bandwidth, "bandwidth", "b", 1, "Bandwidth in GB per day, or GB stored for storage meters")
 	calculatorCmd.Flags().IntVarP(&period, "days", "d", 1, "period (e.g., '1' for 1 day, '7' for 7 days)")
 	calculatorCmd.Flags().Float64VarP(&eventCount, "events", "e", 1, "Number of events (default is 1 for 1 Million events)")
+	addOSFlags(calculatorCmd)
+	addFilterFlags(calculatorCmd)
+	addOutputFlag(calculatorCmd)
+	calculatorCmd.Flags().StringSliceVar(&columnSelection, "columns", calculatorColumns, "Comma separated columns to display, or 'all' (e.g., 'armSkuName,retailPrice,tierMinimumUnits')")
 }
 
-func calculateUsageGB(bandwidth float64, days int, usagePerGB float64) float64 {
-	totalUsage := bandwidth * float64(days) * usagePerGB
-	return totalUsage
-}
-func calculateUsageEvents(eventCount float64, usagePerEvent float64) float64 {
-	totalUsage := eventCount * usagePerEvent
-	return totalUsage
+// writeCalculatorRows writes the rows of a meter with the cost of the usage
+// flags, a row per tier for tiered meters.
+func writeCalculatorRows(out rowWriter, cols []column, rows []priceRow) error {
+	if len(rows) == 1 && rows[0].TierMinimumUnits == 0 {
+		r := rows[0]
+		if unit, err := utils.ParseUnit(r.UnitOfMeasure); err == nil {
+			quantity := calculatorConsumption(unit).Quantity(unit)
+			r.Quantity, r.Usage = &quantity, calculateUsage(r.Item)
+		}
+		return writePriceRow(out, cols, r)
+	}
+	items := make([]utils.Item, len(rows))
+	for i, r := range rows {
+		items[i] = r.Item
+	}
+	unit, err := utils.ParseUnit(items[0].UnitOfMeasure)
+	if err != nil {
+		for _, r := range rows {
+			if err := writePriceRow(out, cols, r); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	tiers, err := utils.TieredCost(items, calculatorConsumption(unit))
+	if err != nil {
+		return err
+	}
+	for _, t := range tiers {
+		quantity := t.Quantity
+		r := priceRow{Item: t.Item, MonthlyPrice: monthlyPrice(t.Item), Quantity: &quantity, Usage: t.Cost}
+		if err := writePriceRow(out, cols, r); err != nil {
+			return err
+		}
+	}
+	return nil
 }
-func calculateUsageHourly(period float64, days int, usagePerHour float64) float64 {
-	hours := days * 24 // convert days to hours
-	totalUsage := period * float64(hours) * usagePerHour
-	return totalUsage
+
+// calculatorConsumption reads the --days, --bandwidth and --events flags as
+// the usage of an item of unit. Bandwidth is read as GB per day for transfer
+// meters and as GB held for storage meters. Events count the items of meters
+// priced per packs of items and period, such as "10 Million/Month", while
+// single items such as "1/Month" are billed once.
+func calculatorConsumption(unit utils.Unit) utils.Consumption {
+	consumption := utils.Consumption{Hours: float64(period * 24), GB: bandwidth * float64(period), Count: eventCount * 1e6}
+	if unit.Dimension == utils.DataTime {
+		consumption.GB = bandwidth
+	}
+	if unit.Dimension == utils.Time && unit.Count <= 1 {
+		consumption.Count = 0
+	}
+	return consumption
 }
-func calculateUsageMonthly(period float64, days int, usagePerMonth float64) float64 {
-	month := days / 30 // convert days to hours
-	totalUsage := period * float64(month) * usagePerMonth
-	return totalUsage
+
+// calculateUsage prices the usage flags against the item's unit of measure.
+func calculateUsage(item utils.Item) float64 {
+	unit, err := utils.ParseUnit(item.UnitOfMeasure)
+	if err != nil {
+		return 0
+	}
+	price := item.RetailPrice
+	if item.Type == "Reservation" {
+		// Reservations are priced for their whole term, spread it over its hours.
+		monthly, ok := utils.MonthlyCost(item)
+		if !ok {
+			return 0
+		}
+		price = monthly / utils.HoursPerMonth * unit.Quantity
+	}
+	return utils.Cost(unit, price, calculatorConsumption(unit))
 }
@@ -0,0 +1,124 @@
+package cmd //Azure Cheapest Region CMD
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/muandane/cloudcost/utils"
+	"github.com/spf13/cobra"
+)
+
+var geography string
+var excludeRegions []string
+var rankPriceType string
+var top int
+
+// cheapestCmd represents the cheapest command
+var cheapestCmd = &cobra.Command{
+	Use:   "cheapest",
+	Short: "Find the regions where a VM size is the cheapest.",
+	Long: `Use the azure cheapest subcommand to rank every region offering a Linux VM size by its
+monthly cost, optionally within a geography such as 'europe', 'us' or 'germany' for data
+residency. Reservations are amortized per month.`,
+	Example: `  cloudcost azure cheapest --sku Standard_E8s_v5 --geo europe --exclude-regions uksouth,ukwest`,
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := resolveOutputFormat()
+		if err != nil {
+			return err
+		}
+		priceType, err := parsePriceType(rankPriceType)
+		if err != nil {
+			return err
+		}
+		if geography != "" {
+			known := false
+			for _, geo := range utils.Geographies() {
+				known = known || utils.NormalizeRegion(geo) == utils.NormalizeRegion(geography)
+			}
+			if !known {
+				return fmt.Errorf("unknown geography %q (available: %s)", geography, strings.Join(utils.Geographies(), ", "))
+			}
+		}
+		excluded := map[string]bool{}
+		for _, r := range excludeRegions {
+			excluded[utils.NormalizeRegion(r)] = true
+		}
+
+		table := utils.NewPriceTable()
+		query := utils.And(utils.Eq("serviceName", "Virtual Machines"), utils.Eq("armSkuName", skuName))
+		err = eachItem(cmd, query, func(item utils.Item) error {
+			if utils.IsWindows(item) || excluded[item.ArmRegionName] {
+				return nil
+			}
+			if geography != "" {
+				if r, ok := utils.LookupRegion(item.ArmRegionName); !ok || !r.InGeography(geography) {
+					return nil
+				}
+			}
+			table.Add(item.ArmRegionName, item)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		table.SortBy(priceType)
+		var rows []string
+		for _, row := range table.Rows {
+			if _, ok := table.Cost(row, priceType); ok && (top <= 0 || len(rows) < top) {
+				rows = append(rows, row)
+			}
+		}
+		if len(rows) == 0 {
+			return fmt.Errorf("no %s price found for %s", priceType, skuName)
+		}
+		table.Rows = rows
+
+		cheapest, _ := table.Cost(rows[0], priceType)
+		rank := map[string]int{}
+		for i, row := range rows {
+			rank[row] = i + 1
+		}
+		return writePriceTable(format, table, priceTableLayout{
+			Keys:    []string{"rank", "region", "displayName", "geography", "pairedRegion"},
+			Headers: []string{"Rank", "Region", "Name", "Geography", "Paired Region"},
+			Lead: func(row string) []any {
+				r, ok := utils.LookupRegion(row)
+				if !ok {
+					r = utils.Region{DisplayName: table.Item(row).Location}
+				}
+				return []any{rank[row], row, r.DisplayName, r.Geography, r.Paired}
+			},
+			TrailKeys:    []string{"vsCheapestPercent"},
+			TrailHeaders: []string{"vs Cheapest %"},
+			Trail: func(row string) []any {
+				cost, _ := table.Cost(row, priceType)
+				if percent, ok := percentChange(cheapest, cost); ok {
+					return []any{percent}
+				}
+				return []any{nil}
+			},
+		})
+	},
+}
+
+func init() {
+	cheapestCmd.Flags().StringVar(&skuName, "sku", "", "VM size to price (e.g., 'Standard_E8s_v5')")
+	cheapestCmd.Flags().StringVar(&geography, "geo", "", "Only rank regions of this geography or group (e.g., 'europe', 'us', 'germany')")
+	cheapestCmd.Flags().StringSliceVar(&excludeRegions, "exclude-regions", nil, "Comma separated regions to leave out")
+	cheapestCmd.Flags().StringVar(&rankPriceType, "price-type", utils.PayAsYouGo, "Price type to rank by: "+strings.Join(utils.PriceTypes, ", "))
+	cheapestCmd.Flags().IntVar(&top, "top", 0, "Only show the first regions (0 shows all)")
+	cheapestCmd.Flags().StringVarP(&currency, "currency", "c", "", "Price Currency (e.g., 'USD' or 'EUR')")
+	addOutputFlag(cheapestCmd)
+	cheapestCmd.MarkFlagRequired("sku")
+}
+
+// parsePriceType resolves a price type name, ignoring case and spaces.
+func parsePriceType(name string) (string, error) {
+	for _, priceType := range utils.PriceTypes {
+		if utils.NormalizeRegion(priceType) == utils.NormalizeRegion(name) {
+			return priceType, nil
+		}
+	}
+	return "", fmt.Errorf("unknown price type %q (available: %s)", name, strings.Join(utils.PriceTypes, ", "))
+}
@@ -0,0 +1,133 @@
+package cmd //Azure SKU Comparison CMD
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/muandane/cloudcost/utils"
+	"github.com/spf13/cobra"
+)
+
+var compareSkus []string
+
+// compareCmd represents the compare command
+var compareCmd = &cobra.Command{
+	Use:   "compare",
+	Short: "Compare VM sizes side by side in a region.",
+	Long: `Use the azure compare subcommand to line VM sizes up with their monthly cost on Linux and
+Windows, pay as you go, Spot, Low Priority and reserved for 1 or 3 years, with the
+difference of the pay as you go cost from the first size. Reservations only cover compute,
+so the Windows licence is added to them at its pay as you go price.`,
+	Example: `  cloudcost azure compare --sku Standard_D4s_v5 --sku Standard_D4as_v5 --sku Standard_E4s_v5 --region westeurope`,
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := resolveOutputFormat()
+		if err != nil {
+			return err
+		}
+		catalog, err := loadPerCatalog()
+		if err != nil {
+			return err
+		}
+		var skuFilters []utils.Filter
+		for _, sku := range compareSkus {
+			skuFilters = append(skuFilters, utils.Eq("armSkuName", sku))
+		}
+		query := utils.And(utils.Eq("serviceName", "Virtual Machines"), utils.Eq("armRegionName", utils.NormalizeRegion(region)), utils.Or(skuFilters...))
+
+		table := utils.NewPriceTable()
+		base := map[bool]float64{}
+		err = eachItem(cmd, query, func(item utils.Item) error {
+			table.Add(compareRow(item.ArmSkuName, utils.IsWindows(item)), item)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		// Rows follow the order of --sku, Linux first.
+		var rows []string
+		for _, sku := range compareSkus {
+			linux, windows := compareRow(sku, false), compareRow(sku, true)
+			addWindowsReservations(table, linux, windows)
+			for _, row := range []string{linux, windows} {
+				if _, ok := table.Cost(row, utils.PayAsYouGo); ok {
+					rows = append(rows, row)
+				}
+			}
+			if _, ok := table.Cost(linux, utils.PayAsYouGo); !ok {
+				return fmt.Errorf("no price found for %s in %s", sku, region)
+			}
+		}
+		table.Rows = rows
+
+		layout := priceTableLayout{
+			Keys:    []string{"armSkuName", "os"},
+			Headers: []string{"SKU", "OS"},
+			Lead: func(row string) []any {
+				item := table.Item(row)
+				os := "Linux"
+				if utils.IsWindows(item) {
+					os = "Windows"
+				}
+				return []any{item.ArmSkuName, os}
+			},
+			TrailKeys:    []string{"deltaPercent"},
+			TrailHeaders: []string{"vs First %"},
+			Trail: func(row string) []any {
+				windows := utils.IsWindows(table.Item(row))
+				cost, _ := table.Cost(row, utils.PayAsYouGo)
+				if base[windows] == 0 {
+					return []any{nil}
+				}
+				percent, _ := percentChange(base[windows], cost)
+				return []any{percent}
+			},
+		}
+		// Normalized prices are ranked, so that families compare like for
+		// like.
+		if catalog != nil {
+			table, layout = normalizePriceTable(table, layout, catalog)
+			table.SortBy(utils.PayAsYouGo)
+		}
+		base[false], _ = table.Cost(compareRow(compareSkus[0], false), utils.PayAsYouGo)
+		base[true], _ = table.Cost(compareRow(compareSkus[0], true), utils.PayAsYouGo)
+		return writePriceTable(format, table, layout)
+	},
+}
+
+func init() {
+	compareCmd.Flags().StringArrayVar(&compareSkus, "sku", nil, "VM size to compare, repeatable (e.g., 'Standard_D4s_v5')")
+	compareCmd.Flags().StringVarP(&region, "region", "r", "", "Region")
+	compareCmd.Flags().StringVarP(&currency, "currency", "c", "", "Price Currency (e.g., 'USD' or 'EUR')")
+	addPerFlag(compareCmd)
+	addOutputFlag(compareCmd)
+	compareCmd.MarkFlagRequired("sku")
+	compareCmd.MarkFlagRequired("region")
+}
+
+// compareRow is the row of a VM size and OS. Sizes are matched ignoring case,
+// like the API does, so that --sku may be typed in any case.
+func compareRow(sku string, windows bool) string {
+	sku = strings.ToLower(sku)
+	if windows {
+		return sku + " Windows"
+	}
+	return sku + " Linux"
+}
+
+// addWindowsReservations prices the reservations of the Windows row as the
+// Linux reservation plus the licence, the difference of their pay as you go
+// costs.
+func addWindowsReservations(table *utils.PriceTable, linux, windows string) {
+	linuxCost, ok := table.Cost(linux, utils.PayAsYouGo)
+	windowsCost, ok2 := table.Cost(windows, utils.PayAsYouGo)
+	if !ok || !ok2 {
+		return
+	}
+	for _, term := range []string{utils.Reserved1Y, utils.Reserved3Y} {
+		if reserved, ok := table.Cost(linux, term); ok {
+			table.Set(windows, term, reserved+windowsCost-linuxCost)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package cmd //Azure Region Comparison CMD
+
+import (
+	"errors"
+	"os"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muandane/cloudcost/utils"
+	"github.com/spf13/cobra"
+)
+
+var skuName string
+var regions []string
+var allRegions bool
+
+// compareRegionsCmd represents the compare-regions command
+var compareRegionsCmd = &cobra.Command{
+	Use:   "compare-regions",
+	Short: "Compare the price of a VM size across regions.",
+	Long: `Use the azure compare-regions subcommand to get the monthly cost of a Linux VM size in
+several regions, pay as you go, Spot, Low Priority and reserved for 1 or 3 years, with
+reservations amortized per month. Regions are sorted by monthly cost and the cheapest
+region of every price type is highlighted.`,
+	Example: `  cloudcost azure compare-regions --sku Standard_D4s_v5 --regions westeurope,northeurope,francecentral`,
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := resolveOutputFormat()
+		if err != nil {
+			return err
+		}
+		if len(regions) == 0 && !allRegions {
+			return errors.New("no region given, use --regions or --all-regions")
+		}
+		catalog, err := loadPerCatalog()
+		if err != nil {
+			return err
+		}
+		filters := []utils.Filter{utils.Eq("serviceName", "Virtual Machines"), utils.Eq("armSkuName", skuName)}
+		var regionFilters []utils.Filter
+		for _, r := range regions {
+			regionFilters = append(regionFilters, utils.Eq("armRegionName", utils.NormalizeRegion(r)))
+		}
+		filters = append(filters, utils.Or(regionFilters...))
+
+		table := utils.NewPriceTable()
+		err = eachItem(cmd, utils.And(filters...), func(item utils.Item) error {
+			if !utils.IsWindows(item) {
+				table.Add(item.ArmRegionName, item)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		layout := priceTableLayout{
+			Keys:    []string{"region", "location"},
+			Headers: []string{"Region", "Location"},
+			Lead: func(row string) []any {
+				return []any{row, table.Item(row).Location}
+			},
+		}
+		if catalog != nil {
+			table, layout = normalizePriceTable(table, layout, catalog)
+		}
+		table.SortBy(utils.PriceTypes...)
+		return writePriceTable(format, table, layout)
+	},
+}
+
+func init() {
+	compareRegionsCmd.Flags().StringVar(&skuName, "sku", "", "VM size to compare (e.g., 'Standard_D4s_v5')")
+	compareRegionsCmd.Flags().StringSliceVar(&regions, "regions", nil, "Comma separated regions to compare (e.g., 'westeurope,northeurope')")
+	compareRegionsCmd.Flags().BoolVar(&allRegions, "all-regions", false, "Compare every region the VM size is available in")
+	compareRegionsCmd.Flags().StringVarP(&currency, "currency", "c", "", "Price Currency (e.g., 'USD' or 'EUR')")
+	addPerFlag(compareRegionsCmd)
+	addOutputFlag(compareRegionsCmd)
+	compareRegionsCmd.MarkFlagRequired("sku")
+	compareRegionsCmd.MarkFlagsMutuallyExclusive("regions", "all-regions")
+}
+
+// priceTypeKeys are the output keys of the utils.PriceTypes columns.
+var priceTypeKeys = []string{"payAsYouGo", "spot", "lowPriority", "reserved1y", "reserved3y"}
+var priceTypeHeaders = []string{"Pay As You Go", "Spot", "Low Priority", "1 Year Reserved", "3 Years Reserved"}
+
+// priceTableLayout names the columns around the price type columns of a
+// price table. Lead and Trail return the cells of a row for them. Per names
+// the capacity the costs are normalized by, if any.
+type priceTableLayout struct {
+	Keys         []string
+	Headers      []string
+	Lead         func(row string) []any
+	TrailKeys    []string
+	TrailHeaders []string
+	Trail        func(row string) []any
+	Per          string
+}
+
+// writePriceTable prints a row per table row with its monthly cost per price
+// type, the cheapest cell of every price type highlighted.
+func writePriceTable(format string, table *utils.PriceTable, layout priceTableLayout) error {
+	typeKeys, typeHeaders := priceTypeKeys, priceTypeHeaders
+	if layout.Per != "" {
+		typeKeys, typeHeaders = make([]string, len(priceTypeKeys)), make([]string, len(priceTypeHeaders))
+		for i := range priceTypeKeys {
+			typeKeys[i] = priceTypeKeys[i] + "Per" + perLabels[layout.Per][0]
+			typeHeaders[i] = priceTypeHeaders[i] + " / " + perLabels[layout.Per][1]
+		}
+	}
+	keys := append(append(append([]string{}, layout.Keys...), typeKeys...), layout.TrailKeys...)
+	headers := append(append(append([]string{}, layout.Headers...), typeHeaders...), layout.TrailHeaders...)
+	lead := len(layout.Keys)
+	cheapest := make([]any, len(utils.PriceTypes))
+	for i, priceType := range utils.PriceTypes {
+		if cost, ok := table.Cost(table.Cheapest(priceType), priceType); ok {
+			cheapest[i] = cost
+		}
+	}
+	out, err := newRowWriter(os.Stdout, format, keys, headers, func(values []any, col int) lipgloss.TerminalColor {
+		if col >= lead && col < lead+len(cheapest) && values[col] != nil && values[col] == cheapest[col-lead] {
+			return typeColors.Normal
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, row := range table.Rows {
+		values := layout.Lead(row)
+		for _, priceType := range utils.PriceTypes {
+			if cost, ok := table.Cost(row, priceType); ok {
+				values = append(values, cost)
+			} else {
+				values = append(values, nil)
+			}
+		}
+		if layout.Trail != nil {
+			values = append(values, layout.Trail(row)...)
+		}
+		if err := out.Write(values); err != nil {
+			return err
+		}
+	}
+	return out.Close()
+}
@@ -0,0 +1,93 @@
+package cmd //Azure Price Diff CMD
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muandane/cloudcost/utils"
+	"github.com/spf13/cobra"
+)
+
+var changeKinds []string
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff <old.jsonl.gz> <new.jsonl.gz>",
+	Short: "Compare the prices of two snapshots.",
+	Long: `Use the azure diff subcommand to list the prices added, removed, increased or decreased
+between two snapshots written by 'azure snapshot pull'. Prices are matched by meter, SKU,
+price type, region and tier. Snapshots are sorted on disk, so they can be of any size.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := resolveOutputFormat()
+		if err != nil {
+			return err
+		}
+		kinds := map[string]bool{}
+		for _, kind := range changeKinds {
+			switch kind = strings.ToLower(strings.TrimSpace(kind)); kind {
+			case utils.PriceAdded, utils.PriceRemoved, utils.PriceIncreased, utils.PriceDecreased:
+				kinds[kind] = true
+			default:
+				return fmt.Errorf("unknown change %q (available: added, removed, increased, decreased)", kind)
+			}
+		}
+		filter, err := flagFilter(cmd)
+		if err != nil {
+			return err
+		}
+
+		out, err := newRowWriter(os.Stdout, format, diffKeys, diffHeaders, func(values []any, col int) lipgloss.TerminalColor {
+			switch values[0] {
+			case utils.PriceIncreased:
+				return typeColors.Spot
+			case utils.PriceDecreased:
+				return typeColors.Normal
+			case utils.PriceAdded:
+				return typeColors.Low
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		counts := map[string]int{}
+		err = utils.DiffSnapshots(cmd.Context(), args[0], args[1], utils.DiffOptions{Filter: filter}, func(c utils.PriceChange) error {
+			if !kinds[c.Kind] {
+				return nil
+			}
+			counts[c.Kind]++
+			item := c.Item()
+			values := []any{c.Kind, item.ServiceName, item.ProductName, item.SkuName, item.MeterName, item.ArmRegionName, item.Type, item.TierMinimumUnits, item.UnitOfMeasure, nil, nil, nil}
+			if c.Old != nil {
+				values[9] = c.Old.RetailPrice
+			}
+			if c.New != nil {
+				values[10] = c.New.RetailPrice
+			}
+			if percent, ok := c.Percent(); ok {
+				values[11] = percent
+			}
+			return out.Write(values)
+		})
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+		fmt.Fprintf(os.Stderr, "%d added, %d removed, %d increased, %d decreased.\n",
+			counts[utils.PriceAdded], counts[utils.PriceRemoved], counts[utils.PriceIncreased], counts[utils.PriceDecreased])
+		return err
+	},
+}
+
+func init() {
+	diffCmd.Flags().StringVarP(&region, "region", "r", "", "Only compare the prices of this region")
+	diffCmd.Flags().StringVarP(&service, "service", "s", "", "Only compare the prices of services containing this name")
+	diffCmd.Flags().StringSliceVar(&changeKinds, "changes", []string{utils.PriceAdded, utils.PriceRemoved, utils.PriceIncreased, utils.PriceDecreased}, "Comma separated changes to list")
+	addFilterFlags(diffCmd)
+	addOutputFlag(diffCmd)
+}
+
+var diffKeys = []string{"change", "serviceName", "productName", "skuName", "meterName", "armRegionName", "type", "tierMinimumUnits", "unitOfMeasure", "oldPrice", "newPrice", "changePercent"}
+var diffHeaders = []string{"Change", "Service", "Product", "SKU", "Meter", "Region", "Type", "Tier", "Unit of Measure", "Old Price", "New Price", "Change %"}
@@ -0,0 +1,134 @@
+package cmd //Azure Bill of Materials Estimate CMD
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muandane/cloudcost/utils"
+	"github.com/spf13/cobra"
+)
+
+var specFile string
+var environment string
+var specVariables map[string]string
+
+// estimateCmd represents the estimate command
+var estimateCmd = &cobra.Command{
+	Use:   "estimate",
+	Short: "Estimate the monthly cost of a bill of materials.",
+	Long: `Use the azure estimate subcommand to price every resource listed in a YAML or JSON
+file and get the per-line and total monthly and annual cost. Example file:
+
+  currency: EUR
+  region: westeurope
+  variables:
+    vmSize: Standard_D4s_v5
+    nodes: 2
+  environments:
+    prod:
+      variables:
+        nodes: 6
+      resources:
+        logs:
+          usage:
+            gb: 500
+  resources:
+    - name: app
+      service: Virtual Machines
+      sku: ${vmSize}
+      quantity: ${nodes}
+    - name: logs
+      service: Log Analytics
+      meter: Data Ingestion
+      usage:
+        gb: 50`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := resolveOutputFormat()
+		if err != nil {
+			return err
+		}
+		if err := checkBudgetFlags(cmd); err != nil {
+			return err
+		}
+		spec, err := utils.LoadSpec(specFile, environment, specVariables)
+		if err != nil {
+			return err
+		}
+		if currency == "" {
+			currency = spec.Currency
+		}
+		if region == "" {
+			region = spec.Region
+		}
+
+		source, err := newSource()
+		if err != nil {
+			return err
+		}
+		estimator := &utils.Estimator{Prices: source, Region: region}
+		lines, err := estimator.Estimate(cmd.Context(), spec.Resources)
+		if err != nil {
+			return lookupFailed(err)
+		}
+		report := estimateReport(lines)
+		report.Title = "Cost estimate of " + specFile
+		if environment != "" {
+			report.Title += " (" + environment + ")"
+		}
+		return report.finish(cmd, format)
+	},
+}
+
+func init() {
+	estimateCmd.Flags().StringVarP(&specFile, "file", "f", "", "YAML or JSON file listing the resources to price")
+	estimateCmd.Flags().StringVarP(&environment, "env", "E", "", "Environment of the file to apply (e.g., 'dev' or 'prod')")
+	estimateCmd.Flags().StringToStringVar(&specVariables, "var", nil, "Override a variable of the file, repeatable (e.g., 'vmSize=Standard_D8s_v5')")
+	estimateCmd.Flags().StringVarP(&region, "region", "r", "", "Default region for resources that do not set one")
+	estimateCmd.Flags().StringVarP(&currency, "currency", "c", "", "Price Currency (e.g., 'USD' or 'EUR')")
+	addOutputFlag(estimateCmd)
+	addBudgetFlags(estimateCmd, true)
+	estimateCmd.MarkFlagRequired("file")
+}
+
+var estimateKeys = []string{"name", "service", "sku", "region", "meterName", "unitOfMeasure", "retailPrice", "quantity", "monthlyCost", "annualCost", "note"}
+var estimateHeaders = []string{"Name", "Service", "SKU", "Region", "Meter", "Unit of Measure", "Retail Price", "Quantity", "Monthly Cost", "Annual Cost", "Note"}
+
+// estimateReport lists one row per line followed by the total. Unpriced lines
+// carry the reason in the note column and are also reported on stderr.
+func estimateReport(lines []utils.Line) costReport {
+	report := costReport{Keys: estimateKeys, Headers: estimateHeaders, Color: func(values []any, col int) lipgloss.TerminalColor {
+		if values[len(values)-1] != "" {
+			return typeColors.Spot
+		}
+		if values[0] == "TOTAL" {
+			return typeColors.Normal
+		}
+		return nil
+	}}
+	for _, line := range lines {
+		r := line.Resource
+		quantity := 1.0
+		if r.Quantity != nil {
+			quantity = *r.Quantity
+		}
+		values := []any{r.Name, r.Service, r.SKU, r.Region, "", "", nil, quantity, nil, nil, ""}
+		if line.Item != nil {
+			values[2] = line.Item.ArmSkuName
+			if values[2] == "" {
+				values[2] = line.Item.SkuName
+			}
+			values[1], values[4], values[5], values[6] = line.Item.ServiceName, line.Item.MeterName, line.Item.UnitOfMeasure, line.Item.RetailPrice
+			values[8], values[9] = line.Monthly, line.Monthly*12
+			report.Monthly += line.Monthly
+		}
+		if line.Err != nil {
+			values[10] = line.Err.Error()
+			report.Unpriced = append(report.Unpriced, r.Name)
+			fmt.Fprintf(os.Stderr, "Warning: %s not priced: %v\n", r.Name, line.Err)
+		}
+		report.Rows = append(report.Rows, values)
+	}
+	report.Rows = append(report.Rows, []any{"TOTAL", "", "", "", "", "", nil, nil, report.Monthly, report.Monthly * 12, ""})
+	return report
+}
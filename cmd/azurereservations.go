@@ -0,0 +1,85 @@
+package cmd //Azure Reservations CMD
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muandane/cloudcost/utils"
+	"github.com/spf13/cobra"
+)
+
+// reservationsCmd represents the reservations command
+var reservationsCmd = &cobra.Command{
+	Use:   "reservations",
+	Short: "Compare pay as you go with savings plans and reservations for a VM size.",
+	Long: `Use the azure reservations subcommand to get the effective monthly cost of a Linux VM size
+in a region paid as you go, with a savings plan and reserved for 1 or 3 years, reservations
+amortized over their term. Savings are against pay as you go, and the break-even is the
+utilization, the share of the month the VM runs, below which the commitment costs more than
+paying as you go.`,
+	Example: `  cloudcost azure reservations --sku Standard_D4s_v5 --region westeurope`,
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := resolveOutputFormat()
+		if err != nil {
+			return err
+		}
+		query := utils.And(utils.Eq("serviceName", "Virtual Machines"), utils.Eq("armRegionName", utils.NormalizeRegion(region)), utils.Eq("armSkuName", skuName))
+		var items []utils.Item
+		err = eachItem(cmd, query, func(item utils.Item) error {
+			if !utils.IsWindows(item) {
+				items = append(items, item)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		commitments, ok := utils.Commitments(items)
+		if !ok {
+			return fmt.Errorf("no pay as you go price found for %s in %s", skuName, region)
+		}
+		return writeCommitments(format, commitments)
+	},
+}
+
+func init() {
+	reservationsCmd.Flags().StringVar(&skuName, "sku", "", "VM size to price (e.g., 'Standard_D4s_v5')")
+	reservationsCmd.Flags().StringVarP(&region, "region", "r", "", "Region")
+	reservationsCmd.Flags().StringVarP(&currency, "currency", "c", "", "Price Currency (e.g., 'USD' or 'EUR')")
+	addOutputFlag(reservationsCmd)
+	reservationsCmd.MarkFlagRequired("sku")
+	reservationsCmd.MarkFlagRequired("region")
+}
+
+var commitmentKeys = []string{"offer", "term", "monthlyCost", "termCost", "savingsPercent", "breakEvenPercent"}
+var commitmentHeaders = []string{"Offer", "Term", "Monthly Cost", "Term Cost", "Savings %", "Break-even Utilization %"}
+
+// writeCommitments prints a row per commitment, the cheapest highlighted.
+func writeCommitments(format string, commitments []utils.Commitment) error {
+	payg := commitments[0].Monthly
+	cheapest := payg
+	for _, c := range commitments {
+		cheapest = min(cheapest, c.Monthly)
+	}
+	out, err := newRowWriter(os.Stdout, format, commitmentKeys, commitmentHeaders, func(values []any, col int) lipgloss.TerminalColor {
+		if values[2] == cheapest {
+			return typeColors.Normal
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, c := range commitments {
+		values := []any{"Pay As You Go", nil, c.Monthly, nil, nil, nil}
+		if c.Kind != utils.PayAsYouGo {
+			values = []any{c.Kind, c.Term, c.Monthly, c.Total, c.Savings(payg), c.BreakEven(payg)}
+		}
+		if err := out.Write(values); err != nil {
+			return err
+		}
+	}
+	return out.Close()
+}
@@ -1,23 +1,27 @@
 package cmd //Azure Price Search CMD
 
 import (
-	"fmt"
-	"net/url"
-	"os"
+	"slices"
+	"sort"
 	"strings"
 
-	"github.com/charmbracelet/lipgloss"
-	"github.com/charmbracelet/lipgloss/table"
-	"github.com/muandane/cloudcost/utils"
 	"github.com/spf13/cobra"
 )
 
+var sortColumn string
+
 func init() {
 	searchCmd.Flags().StringVarP(&vmType, "type", "t", "", "VM type")
 	searchCmd.Flags().StringVarP(&region, "region", "r", "", "Region")
 	searchCmd.Flags().StringVarP(&service, "service", "s", "", "Azure service (e.g., 'D' for D series vms, Private for Private links)")
 	searchCmd.Flags().StringVarP(&pricingType, "pricing-type", "p", "Consumption", "Pricing Type (e.g., 'Consumption' or 'Reservation')")
 	searchCmd.Flags().StringVarP(&currency, "currency", "c", "", "Price Currency (e.g., 'USD' or 'EUR')")
+	addOSFlags(searchCmd)
+	addFilterFlags(searchCmd)
+	addPerFlag(searchCmd)
+	searchCmd.Flags().StringVar(&sortColumn, "sort", "", "Sort the rows by a column, descending with a leading '-' (e.g., 'monthlyPerGib')")
+	addOutputFlag(searchCmd)
+	searchCmd.Flags().StringSliceVar(&columnSelection, "columns", searchColumns, "Comma separated columns to display, or 'all' (e.g., 'armSkuName,retailPrice,reservationTerm')")
 }
 
 var searchCmd = &cobra.Command{
@@ -26,72 +30,80 @@ var searchCmd = &cobra.Command{
 	Long: `Use the azure search subcommand to search for a specific Azure resource 
 and retrieve its pricing information. Provide the resource name 
 as an argument to this command.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		re := lipgloss.NewRenderer(os.Stdout)
-		baseStyle := re.NewStyle().Padding(0, 1)
-		headerStyle := baseStyle.Copy().Foreground(lipgloss.AdaptiveColor{Light: "#186F65", Dark: "#1AACAC"}).Bold(true)
-
-		tableData := [][]string{{"SKU", "Retail Price", "Unit of Measure", "Monthly Price", "Region", "Meter", "Product Name"}}
-		apiURL := "https://prices.azure.com/api/retail/prices?"
-		currencyType := fmt.Sprintf("currencyCode='%s'", currency)
-		query := utils.Query(region, service, vmType, pricingType)
-		escapedQuery := url.QueryEscape(query)
-		for {
-			var resp utils.Response
-			err := utils.GetJSON(apiURL+currencyType+"&$filter="+escapedQuery, &resp)
-			if err != nil {
-				fmt.Println("Error:", err)
-				return
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := resolveOutputFormat()
+		if err != nil {
+			return err
+		}
+		catalog, err := loadPerCatalog()
+		if err != nil {
+			return err
+		}
+		cols, err := selectColumns(cmd, perColumns(hybridColumns(searchColumns)))
+		if err != nil {
+			return err
+		}
+		var sortBy column
+		if sortColumn != "" {
+			if sortBy, err = findColumn(strings.TrimPrefix(sortColumn, "-")); err != nil {
+				return err
 			}
+		}
+		joinSizes := slices.ContainsFunc(append(slices.Clone(cols), sortBy), func(c column) bool {
+			return slices.Contains(vmSizeColumns, c.Key)
+		})
+		if catalog == nil && joinSizes {
+			if catalog, err = loadVMCatalog(); err != nil {
+				return err
+			}
+		}
+		query, err := buildFilter(cmd)
+		if err != nil {
+			return err
+		}
 
-			for _, item := range resp.Items {
-				var monthlyPrice string
-				if pricingType != "Reservation" && !strings.Contains(item.UnitOfMeasure, "GB") && !strings.Contains(item.UnitOfMeasure, "Month") && !strings.Contains(item.UnitOfMeasure, "M") && !strings.Contains(item.UnitOfMeasure, "K") {
-					monthlyPrice = fmt.Sprintf("%v", item.RetailPrice*730) // Calculate the monthly price
-				} else {
-					monthlyPrice = "---"
+		out, err := newPriceWriter(format, cols)
+		if err != nil {
+			return err
+		}
+		// Rows are streamed unless they have to be sorted.
+		var rows []priceRow
+		err = eachPriceRow(cmd, query, func(r priceRow) error {
+			if catalog != nil {
+				if size, ok := catalog.Lookup(r.ArmSkuName); ok {
+					r.Size = &size
 				}
-				tableData = append(tableData, []string{item.ArmSkuName, fmt.Sprintf("%f", item.RetailPrice), item.UnitOfMeasure, fmt.Sprintf("%v", monthlyPrice), item.MeterName, item.ArmRegionName, item.ProductName})
 			}
-			if resp.NextPageLink == "" {
-				break
+			if sortColumn != "" {
+				rows = append(rows, r)
+				return nil
 			}
-			apiURL = resp.NextPageLink
+			return writePriceRow(out, cols, r)
+		})
+		if err == nil && sortColumn != "" {
+			err = writeSortedRows(out, cols, rows, sortBy, strings.HasPrefix(sortColumn, "-"))
 		}
-
-		headers := []string{"SKU", "Retail Price", "Unit of Measure", "Monthly Price", "Meter", "Region", "Product Name"}
-		CapitalizeHeaders := func(tableData []string) []string {
-			for i := range tableData {
-				tableData[i] = strings.ToUpper(tableData[i])
-			}
-			return tableData
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
 		}
-
-		t := table.New().
-			Border(lipgloss.NormalBorder()).
-			BorderStyle(re.NewStyle().Foreground(lipgloss.AdaptiveColor{Light: "#186F65", Dark: "#1AACAC"})).
-			Headers(CapitalizeHeaders(headers)...).
-			Width(120).
-			Rows(tableData[1:]...). // Pass only the rows to the Rows function
-			StyleFunc(func(row, col int) lipgloss.Style {
-				if row == 0 {
-					return headerStyle
-				}
-				if col == 4 {
-					// Check if the "Meter" column contains "Spot" or "Low"
-					meter := tableData[row-0][4]                                       // The "Meter" column is the 5th column (index 4)
-					color := lipgloss.AdaptiveColor{Light: "#186F65", Dark: "#1AACAC"} // Default color
-					if strings.Contains(meter, "Spot") {
-						color = typeColors.Spot
-					} else if strings.Contains(meter, "Low") {
-						color = typeColors.Low
-					} else {
-						color = typeColors.Normal
-					}
-					return baseStyle.Copy().Foreground(color)
-				}
-				return baseStyle.Copy().Foreground(lipgloss.AdaptiveColor{Light: "#053B50", Dark: "#F1EFEF"})
-			})
-		fmt.Println(t)
+		return err
 	},
 }
+
+// writeSortedRows writes rows ordered by the value of column, empty values
+// last.
+func writeSortedRows(out rowWriter, cols []column, rows []priceRow, by column, desc bool) error {
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, b := by.Value(rows[i]), by.Value(rows[j])
+		if desc && a != nil && b != nil {
+			a, b = b, a
+		}
+		return lessCell(a, b)
+	})
+	for _, r := range rows {
+		if err := writePriceRow(out, cols, r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package cmd //Azure Price Snapshot CMD
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/muandane/cloudcost/utils"
+	"github.com/spf13/cobra"
+)
+
+var snapshotOutput string
+
+// snapshotCmd represents the snapshot command
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Save the price catalog to a file for offline and reproducible queries.",
+	Long: `Use the azure snapshot subcommands to download the Retail Prices catalog, or part of
+it, to a compressed file. Every azure subcommand accepts --snapshot <file> to query the
+file instead of the API, with the same filters:
+
+  cloudcost azure snapshot pull --currency EUR -o prices.jsonl.gz
+  cloudcost azure estimate -f stack.yaml --snapshot prices.jsonl.gz`,
+}
+
+var snapshotPullCmd = &cobra.Command{
+	Use:   "pull",
+	Short: "Download the price catalog to a snapshot file.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		filter, err := flagFilter(cmd)
+		if err != nil {
+			return err
+		}
+		// Parts of the catalog are fetched concurrently, their pages in sequence.
+		client := utils.NewClient(currency)
+		client.Concurrency = 1
+		start := time.Now()
+		header, err := utils.PullSnapshot(cmd.Context(), client, snapshotOutput, utils.PullOptions{
+			Filter:      filter,
+			Concurrency: concurrency,
+			Progress: func(part string, items int) {
+				fmt.Fprintf(os.Stderr, "%-30s %8d items\n", part, items)
+			},
+		})
+		if err != nil {
+			return lookupFailed(err)
+		}
+		fmt.Fprintf(os.Stderr, "Wrote %d %s prices to %s in %s.\n", header.Items, header.Currency, snapshotOutput, time.Since(start).Round(time.Second))
+		return nil
+	},
+}
+
+var snapshotInfoCmd = &cobra.Command{
+	Use:   "info <file>",
+	Short: "Show the metadata of a snapshot file.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := resolveOutputFormat()
+		if err != nil {
+			return err
+		}
+		s, err := utils.OpenSnapshot(args[0])
+		if err != nil {
+			return err
+		}
+		out, err := newRowWriter(os.Stdout, format,
+			[]string{"file", "version", "currency", "apiVersion", "filter", "createdAt", "items"},
+			[]string{"File", "Version", "Currency", "API Version", "Filter", "Created At", "Items"}, nil)
+		if err != nil {
+			return err
+		}
+		h := s.Header
+		if err := out.Write([]any{args[0], h.Version, h.Currency, h.APIVersion, h.Filter, h.CreatedAt.Format(time.RFC3339), h.Items}); err != nil {
+			return err
+		}
+		return out.Close()
+	},
+}
+
+func init() {
+	snapshotPullCmd.Flags().StringVarP(&snapshotOutput, "output", "o", "", "Snapshot file to write (e.g., 'prices.jsonl.gz')")
+	snapshotPullCmd.Flags().StringVarP(&currency, "currency", "c", "", "Price Currency (e.g., 'USD' or 'EUR')")
+	snapshotPullCmd.Flags().StringVarP(&region, "region", "r", "", "Only pull the prices of this region")
+	snapshotPullCmd.Flags().StringVarP(&service, "service", "s", "", "Only pull the prices of services containing this name")
+	addFilterFlags(snapshotPullCmd)
+	snapshotPullCmd.MarkFlagRequired("output")
+	addOutputFlag(snapshotInfoCmd)
+	snapshotCmd.AddCommand(snapshotPullCmd)
+	snapshotCmd.AddCommand(snapshotInfoCmd)
+}
@@ -0,0 +1,125 @@
+package cmd //Azure Blob Storage Estimate CMD
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/muandane/cloudcost/utils"
+	"github.com/spf13/cobra"
+)
+
+var storageFile string
+var storageTiers []string
+var redundancy string
+
+// storageCmd represents the storage command
+var storageCmd = &cobra.Command{
+	Use:   "storage",
+	Short: "Estimate the cost of storage accounts.",
+	Long:  `Use the azure storage subcommands to price the block blobs of storage accounts.`,
+}
+
+var storageEstimateCmd = &cobra.Command{
+	Use:   "estimate",
+	Short: "Estimate the monthly cost of block blob storage.",
+	Long: `Use the azure storage estimate subcommand to price the capacity, read, write and list
+operations, data retrieval and early deletion of the access tiers of a storage account,
+described in a YAML or JSON file or with flags. A --tier flag naming a tier of the file
+overrides the fields it sets. Data deleted or moved out of the cool, cold or archive tier
+before 30, 90 or 180 days is billed for the remaining days. Example file:
+
+  currency: EUR
+  region: westeurope
+  redundancy: RA-GRS
+  tiers:
+    - tier: hot
+      gb: 2000
+      writeOperations: 5000000
+      readOperations: 40000000
+      listOperations: 100000
+    - tier: archive
+      gb: 100000
+      retrievalGB: 500
+      earlyDeleteGB: 1000
+      earlyDeleteDays: 60`,
+	Example: `  cloudcost azure storage estimate -r westeurope --redundancy ZRS --tier tier=hot,gb=1000,writeOperations=1e6,readOperations=1e7 --tier tier=cool,gb=20000,retrievalGB=200`,
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := resolveOutputFormat()
+		if err != nil {
+			return err
+		}
+		if err := checkBudgetFlags(cmd); err != nil {
+			return err
+		}
+		account := &utils.StorageAccount{}
+		if storageFile != "" {
+			if account, err = utils.LoadStorageAccount(storageFile); err != nil {
+				return err
+			}
+		}
+		for _, s := range storageTiers {
+			tier, err := utils.ParseStorageTier(s)
+			if err != nil {
+				return err
+			}
+			// A tier of the file only has the fields the flag sets replaced.
+			merged := false
+			for i := range account.Tiers {
+				if strings.EqualFold(account.Tiers[i].Tier, tier.Tier) {
+					if err := account.Tiers[i].Apply(s); err != nil {
+						return err
+					}
+					merged = true
+				}
+			}
+			if !merged {
+				account.Tiers = append(account.Tiers, tier)
+			}
+		}
+		if redundancy != "" {
+			account.Redundancy = redundancy
+		}
+		if currency == "" {
+			currency = account.Currency
+		}
+		if region == "" {
+			region = account.Region
+		}
+		if region == "" {
+			return fmt.Errorf("set the region with --region or in the storage file")
+		}
+		account.Region = region
+		resources, err := account.Resources()
+		if err != nil {
+			return err
+		}
+
+		source, err := newSource()
+		if err != nil {
+			return err
+		}
+		estimator := &utils.Estimator{Prices: source, Region: region}
+		lines, err := estimator.Estimate(cmd.Context(), resources)
+		if err != nil {
+			return lookupFailed(err)
+		}
+		report := estimateReport(lines)
+		report.Title = "Blob storage cost estimate"
+		if storageFile != "" {
+			report.Title += " of " + storageFile
+		}
+		return report.finish(cmd, format)
+	},
+}
+
+func init() {
+	storageEstimateCmd.Flags().StringVarP(&storageFile, "file", "f", "", "YAML or JSON file describing the storage account")
+	storageEstimateCmd.Flags().StringArrayVar(&storageTiers, "tier", nil, "Access tier usage as key=value pairs, repeatable (e.g., 'tier=cool,gb=5000,readOperations=1e6,retrievalGB=100')")
+	storageEstimateCmd.Flags().StringVar(&redundancy, "redundancy", "", "Redundancy: "+strings.Join(utils.BlobRedundancies, ", ")+" (default LRS)")
+	storageEstimateCmd.Flags().StringVarP(&region, "region", "r", "", "Region")
+	storageEstimateCmd.Flags().StringVarP(&currency, "currency", "c", "", "Price Currency (e.g., 'USD' or 'EUR')")
+	addOutputFlag(storageEstimateCmd)
+	addBudgetFlags(storageEstimateCmd, true)
+	storageCmd.AddCommand(storageEstimateCmd)
+}
@@ -0,0 +1,90 @@
+package cmd //Azure Terraform Plan Estimate CMD
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muandane/cloudcost/utils"
+	"github.com/spf13/cobra"
+)
+
+var storageGB float64
+
+// terraformCmd represents the terraform command
+var terraformCmd = &cobra.Command{
+	Use:   "terraform <plan.json>",
+	Short: "Estimate the monthly cost of a Terraform plan.",
+	Long: `Use the azure terraform subcommand to price the azurerm resources of a plan before
+and after it is applied. The plan is the output of:
+
+  terraform plan -out plan.tfplan
+  terraform show -json plan.tfplan > plan.json
+
+Resources that cannot be priced are listed with the reason instead of being skipped.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := resolveOutputFormat()
+		if err != nil {
+			return err
+		}
+		plan, err := utils.LoadPlan(args[0])
+		if err != nil {
+			return err
+		}
+		source, err := newSource()
+		if err != nil {
+			return err
+		}
+		estimator := &utils.Estimator{Prices: source, Region: region}
+		costs, err := estimator.EstimatePlan(cmd.Context(), plan, utils.TerraformOptions{Region: region, StorageGB: storageGB})
+		if err != nil {
+			return lookupFailed(err)
+		}
+		report := planReport(costs)
+		report.Title = "Cost of " + args[0]
+		return report.finish(cmd, format)
+	},
+}
+
+func init() {
+	terraformCmd.Flags().StringVarP(&region, "region", "r", "", "Region for resources without a location, such as AKS node pools")
+	terraformCmd.Flags().StringVarP(&currency, "currency", "c", "", "Price Currency (e.g., 'USD' or 'EUR')")
+	terraformCmd.Flags().Float64Var(&storageGB, "storage-gb", 100, "Capacity in GB assumed for each storage account")
+	addOutputFlag(terraformCmd)
+	addBudgetFlags(terraformCmd, false)
+}
+
+var planKeys = []string{"address", "type", "action", "monthlyBefore", "monthlyAfter", "monthlyDelta", "note"}
+var planHeaders = []string{"Address", "Type", "Action", "Monthly Before", "Monthly After", "Delta", "Note"}
+
+// planReport lists one row per resource followed by the totals.
+func planReport(costs []utils.PlanCost) costReport {
+	report := costReport{Keys: planKeys, Headers: planHeaders, Color: func(values []any, col int) lipgloss.TerminalColor {
+		if values[len(values)-1] != "" {
+			return typeColors.Low
+		}
+		if delta, ok := values[5].(float64); ok && col == 5 {
+			if delta > 0 {
+				return typeColors.Spot
+			}
+			return typeColors.Normal
+		}
+		return nil
+	}}
+	var before float64
+	for _, cost := range costs {
+		note := ""
+		if cost.Err != nil {
+			note = cost.Err.Error()
+			report.Unpriced = append(report.Unpriced, cost.Change.Address)
+			fmt.Fprintf(os.Stderr, "Warning: %s not priced: %v\n", cost.Change.Address, cost.Err)
+		}
+		before += cost.BeforeMonthly
+		report.Monthly += cost.AfterMonthly
+		report.Rows = append(report.Rows, []any{cost.Change.Address, cost.Change.Type, cost.Action(), cost.BeforeMonthly, cost.AfterMonthly, cost.AfterMonthly - cost.BeforeMonthly, note})
+	}
+	report.Before = &before
+	report.Rows = append(report.Rows, []any{"TOTAL", "", "", before, report.Monthly, report.Monthly - before, ""})
+	return report
+}
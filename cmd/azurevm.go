@@ -0,0 +1,205 @@
+package cmd //Azure VM Sizes CMD
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/muandane/cloudcost/utils"
+	"github.com/spf13/cobra"
+)
+
+var catalogFile string
+var minVCPUs int
+var maxVCPUs int
+var minMemory float64
+var maxMemory float64
+var vmFeatures []string
+
+// vmCmd represents the vm command
+var vmCmd = &cobra.Command{
+	Use:   "vm",
+	Short: "Find VM sizes by their capabilities.",
+	Long: `Use the azure vm subcommands to search VM sizes by vCPUs, memory and features and rank them
+by price. A catalog of common sizes is bundled; 'azure vm import' replaces it with the output
+of 'az vm list-skus' to cover every size offered to a subscription.`,
+}
+
+var vmImportCmd = &cobra.Command{
+	Use:   "import <list-skus.json>",
+	Short: "Import the VM sizes listed by az vm list-skus.",
+	Long: `Use the azure vm import subcommand to replace the bundled VM size catalog with the output of
+'az vm list-skus -o json', read from a file or from stdin with '-'. Remove the imported file
+to go back to the bundled catalog.`,
+	Example: `  az vm list-skus --resource-type virtualMachines -o json | cloudcost azure vm import -`,
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := vmCatalogPath()
+		if err != nil {
+			return err
+		}
+		in := os.Stdin
+		if args[0] != "-" {
+			f, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			in = f
+		}
+		catalog, err := utils.ImportVMSkus(in)
+		if err != nil {
+			return err
+		}
+		if err := catalog.Save(path); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Imported %d VM sizes to %s\n", len(catalog.Sizes), path)
+		return nil
+	},
+}
+
+var vmFindCmd = &cobra.Command{
+	Use:   "find",
+	Short: "Rank the VM sizes matching requirements by price in a region.",
+	Long: `Use the azure vm find subcommand to list the VM sizes of the catalog with at least (or at
+most) some vCPUs and GiB of memory and all the features asked for, ranked by their monthly
+cost in a region. Features: ` + strings.Join(utils.VMFeatures, ", ") + `.`,
+	Example: `  cloudcost azure vm find --region westeurope --min-vcpu 8 --min-memory 32 --features premiumio,acceleratednetworking`,
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := resolveOutputFormat()
+		if err != nil {
+			return err
+		}
+		priceType, err := parsePriceType(rankPriceType)
+		if err != nil {
+			return err
+		}
+		windows := false
+		switch strings.ToLower(osName) {
+		case "", "linux":
+		case "windows":
+			windows = true
+		default:
+			return fmt.Errorf("unknown operating system %q, expected linux or windows", osName)
+		}
+		for _, feature := range vmFeatures {
+			known := false
+			for _, f := range utils.VMFeatures {
+				known = known || strings.EqualFold(f, feature)
+			}
+			if !known {
+				return fmt.Errorf("unknown feature %q (available: %s)", feature, strings.Join(utils.VMFeatures, ", "))
+			}
+		}
+		if _, err := loadPerCatalog(); err != nil {
+			return err
+		}
+		catalog, err := loadVMCatalog()
+		if err != nil {
+			return err
+		}
+		sizes := map[string]utils.VMSize{}
+		for _, size := range catalog.Sizes {
+			if matchVMSize(size) && size.OfferedIn(region) {
+				sizes[strings.ToLower(size.Name)] = size
+			}
+		}
+		if len(sizes) == 0 {
+			return fmt.Errorf("no VM size of the catalog matches")
+		}
+
+		table := utils.NewPriceTable()
+		query := utils.And(utils.Eq("serviceName", "Virtual Machines"), utils.Eq("armRegionName", utils.NormalizeRegion(region)))
+		err = eachItem(cmd, query, func(item utils.Item) error {
+			if _, ok := sizes[strings.ToLower(item.ArmSkuName)]; ok {
+				table.Add(compareRow(item.ArmSkuName, utils.IsWindows(item)), item)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		var rows []string
+		for _, row := range table.Rows {
+			item := table.Item(row)
+			if utils.IsWindows(item) != windows {
+				continue
+			}
+			if windows {
+				addWindowsReservations(table, compareRow(item.ArmSkuName, false), row)
+			}
+			if _, ok := table.Cost(row, priceType); ok {
+				rows = append(rows, row)
+			}
+		}
+		table.Rows = rows
+		rank := map[string]int{}
+		layout := priceTableLayout{
+			Keys:    []string{"rank", "armSkuName", "vCPUs", "memoryGiB"},
+			Headers: []string{"Rank", "SKU", "vCPUs", "Memory GiB"},
+			Lead: func(row string) []any {
+				size := sizes[strings.ToLower(table.Item(row).ArmSkuName)]
+				return []any{rank[row], size.Name, size.VCPUs, size.MemoryGiB}
+			},
+		}
+		if perUnit != "" {
+			table, layout = normalizePriceTable(table, layout, catalog)
+		}
+		table.SortBy(priceType)
+		if top > 0 && len(table.Rows) > top {
+			table.Rows = table.Rows[:top]
+		}
+		if len(table.Rows) == 0 {
+			return fmt.Errorf("no %s price found in %s for the %d matching VM sizes", priceType, region, len(sizes))
+		}
+		for i, row := range table.Rows {
+			rank[row] = i + 1
+		}
+		return writePriceTable(format, table, layout)
+	},
+}
+
+func init() {
+	vmCmd.PersistentFlags().StringVar(&catalogFile, "catalog", "", "VM size catalog file (defaults to the imported catalog, else the bundled one)")
+	vmFindCmd.Flags().StringVarP(&region, "region", "r", "", "Region")
+	vmFindCmd.Flags().IntVar(&minVCPUs, "min-vcpu", 0, "Minimum number of vCPUs")
+	vmFindCmd.Flags().IntVar(&maxVCPUs, "max-vcpu", 0, "Maximum number of vCPUs (0 for no limit)")
+	vmFindCmd.Flags().Float64Var(&minMemory, "min-memory", 0, "Minimum memory in GiB")
+	vmFindCmd.Flags().Float64Var(&maxMemory, "max-memory", 0, "Maximum memory in GiB (0 for no limit)")
+	vmFindCmd.Flags().StringSliceVar(&vmFeatures, "features", nil, "Comma separated features the size must have (e.g., 'premiumio,acceleratednetworking')")
+	vmFindCmd.Flags().StringVar(&osName, "os", "", "Operating system to price: linux (default) or windows")
+	vmFindCmd.Flags().StringVar(&rankPriceType, "price-type", utils.PayAsYouGo, "Price type to rank by: "+strings.Join(utils.PriceTypes, ", "))
+	vmFindCmd.Flags().IntVar(&top, "top", 0, "Only show the first sizes (0 shows all)")
+	vmFindCmd.Flags().StringVarP(&currency, "currency", "c", "", "Price Currency (e.g., 'USD' or 'EUR')")
+	addPerFlag(vmFindCmd)
+	addOutputFlag(vmFindCmd)
+	vmFindCmd.MarkFlagRequired("region")
+	vmCmd.AddCommand(vmImportCmd)
+	vmCmd.AddCommand(vmFindCmd)
+}
+
+// vmCatalogPath returns --catalog or the default catalog path.
+func vmCatalogPath() (string, error) {
+	if catalogFile != "" {
+		return catalogFile, nil
+	}
+	return utils.DefaultVMCatalogPath()
+}
+
+// matchVMSize reports whether size meets the vCPU, memory and feature flags.
+func matchVMSize(size utils.VMSize) bool {
+	if size.VCPUs < minVCPUs || (maxVCPUs > 0 && size.VCPUs > maxVCPUs) {
+		return false
+	}
+	if size.MemoryGiB < minMemory || (maxMemory > 0 && size.MemoryGiB > maxMemory) {
+		return false
+	}
+	for _, feature := range vmFeatures {
+		if !size.Has(feature) {
+			return false
+		}
+	}
+	return true
+}
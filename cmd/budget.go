@@ -0,0 +1,184 @@
+package cmd //Budget gate and summary of the estimate commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// Exit codes, so that pipelines can tell why a command failed. Other errors
+// exit with 1.
+const (
+	exitOverBudget    = 2
+	exitLookupFailed  = 3
+	exitUnpricedItems = 4
+)
+
+// exitError is an error that makes the process exit with code.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+
+func (e *exitError) Unwrap() error { return e.err }
+
+// lookupFailed marks err as a failure to query the Retail Prices API.
+func lookupFailed(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &exitError{code: exitLookupFailed, err: err}
+}
+
+var maxMonthly float64
+var maxIncrease float64
+var baselineMonthly float64
+var summaryFile string
+
+// addBudgetFlags registers the budget gate flags. Commands that do not know
+// the current cost themselves take it from --baseline.
+func addBudgetFlags(cmd *cobra.Command, baseline bool) {
+	cmd.Flags().Float64Var(&maxMonthly, "max-monthly", 0, "Fail with exit code 2 when the monthly cost is above this amount")
+	cmd.Flags().Float64Var(&maxIncrease, "max-increase", 0, "Fail with exit code 2 when the monthly cost increases by more than this percentage")
+	if baseline {
+		cmd.Flags().Float64Var(&baselineMonthly, "baseline", 0, "Current monthly cost, compared against by --max-increase")
+	}
+	cmd.Flags().StringVar(&summaryFile, "summary", "", "Write a markdown summary, e.g. for a pull request comment, to this file ('-' for stdout)")
+}
+
+// checkBudgetFlags validates the budget flags before anything is priced.
+func checkBudgetFlags(cmd *cobra.Command) error {
+	if cmd.Flags().Changed("max-increase") && cmd.Flags().Lookup("baseline") != nil && !cmd.Flags().Changed("baseline") {
+		return fmt.Errorf("--max-increase needs the current monthly cost, set it with --baseline")
+	}
+	return nil
+}
+
+// costReport is the outcome of an estimate command: the rows printed in the
+// selected format, the monthly cost and what the gate needs to know.
+type costReport struct {
+	Title   string
+	Keys    []string
+	Headers []string
+	Rows    [][]any
+	Color   cellColor
+	// Before is the monthly cost before the change, nil when unknown.
+	Before   *float64
+	Monthly  float64
+	Unpriced []string
+}
+
+// finish writes the report and the summary, then applies the budget gate.
+// Unpriced resources fail the command once the budget is checked.
+func (r costReport) finish(cmd *cobra.Command, format string) error {
+	out, err := newRowWriter(os.Stdout, format, r.Keys, r.Headers, r.Color)
+	if err != nil {
+		return err
+	}
+	for _, values := range r.Rows {
+		if err := out.Write(values); err != nil {
+			return err
+		}
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	if r.Before == nil && cmd.Flags().Changed("baseline") {
+		r.Before = &baselineMonthly
+	}
+	var violations []string
+	if cmd.Flags().Changed("max-monthly") && r.Monthly > maxMonthly {
+		violations = append(violations, fmt.Sprintf("monthly cost %s is above the %s budget", formatCell(r.Monthly, ""), formatCell(maxMonthly, "")))
+	}
+	if cmd.Flags().Changed("max-increase") && r.Before != nil {
+		increase, ok := percentChange(*r.Before, r.Monthly)
+		switch {
+		case !ok && r.Monthly > 0:
+			violations = append(violations, fmt.Sprintf("monthly cost increases from 0 to %s, more than the allowed %s%%", formatCell(r.Monthly, ""), formatCell(maxIncrease, "")))
+		case increase > maxIncrease:
+			violations = append(violations, fmt.Sprintf("monthly cost increases by %.1f%%, more than the allowed %s%%", increase, formatCell(maxIncrease, "")))
+		}
+	}
+
+	if summaryFile != "" {
+		gated := cmd.Flags().Changed("max-monthly") || cmd.Flags().Changed("max-increase")
+		if err := r.writeSummary(gated, violations); err != nil {
+			return err
+		}
+	}
+	if len(violations) > 0 {
+		return &exitError{code: exitOverBudget, err: fmt.Errorf("over budget: %s", strings.Join(violations, "; "))}
+	}
+	if len(r.Unpriced) > 0 {
+		return &exitError{code: exitUnpricedItems, err: fmt.Errorf("%d resource(s) could not be priced: %s", len(r.Unpriced), strings.Join(r.Unpriced, ", "))}
+	}
+	return nil
+}
+
+// percentChange returns the change from before to after in percent. It is
+// not defined, and ok is false, for a change from zero.
+func percentChange(before, after float64) (percent float64, ok bool) {
+	if before == 0 {
+		return 0, after == 0
+	}
+	return (after - before) / before * 100, true
+}
+
+// writeSummary writes the report as markdown to --summary. The budget status
+// is only stated when a budget was given.
+func (r costReport) writeSummary(gated bool, violations []string) error {
+	f := os.Stdout
+	if summaryFile != "-" {
+		var err error
+		if f, err = os.Create(summaryFile); err != nil {
+			return err
+		}
+		defer f.Close()
+	}
+
+	fmt.Fprintf(f, "### %s\n\n", r.Title)
+	if gated && len(violations) > 0 {
+		fmt.Fprint(f, ":x: Over budget\n\n")
+	} else if gated {
+		fmt.Fprint(f, ":white_check_mark: Within budget\n\n")
+	}
+	fmt.Fprintf(f, "**Monthly cost:** %s", formatCell(r.Monthly, ""))
+	if r.Before != nil {
+		if change, ok := percentChange(*r.Before, r.Monthly); ok {
+			fmt.Fprintf(f, " (was %s, %+.1f%%)", formatCell(*r.Before, ""), change)
+		} else {
+			fmt.Fprintf(f, " (was %s)", formatCell(*r.Before, ""))
+		}
+	}
+	fmt.Fprint(f, "\n\n")
+	for _, v := range violations {
+		fmt.Fprintf(f, "- %s\n", v)
+	}
+	if len(r.Unpriced) > 0 {
+		fmt.Fprintf(f, "- :warning: not priced: %s\n", strings.Join(r.Unpriced, ", "))
+	}
+	if len(violations) > 0 || len(r.Unpriced) > 0 {
+		fmt.Fprintln(f)
+	}
+
+	fmt.Fprint(f, "<details><summary>Resources</summary>\n\n")
+	out, err := newRowWriter(f, "markdown", r.Keys, r.Headers, nil)
+	if err != nil {
+		return err
+	}
+	for _, values := range r.Rows {
+		if err := out.Write(values); err != nil {
+			return err
+		}
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	_, err = fmt.Fprint(f, "\n</details>\n")
+	return err
+}
@@ -0,0 +1,110 @@
+/*
+Local price cache CMD
+*/
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	"github.com/muandane/cloudcost/utils"
+	"github.com/spf13/cobra"
+)
+
+var olderThan time.Duration
+
+// cacheCmd represents the cache command
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the local price cache.",
+	Long: `Prices fetched by the azure subcommands are cached under the user cache directory
+and reused until --cache-ttl expires. Use --offline to only read the cache, e.g. on
+build agents without internet access, and --refresh to fetch prices again.`,
+}
+
+var cacheLsCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "List the cached queries.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := resolveOutputFormat()
+		if err != nil {
+			return err
+		}
+		cache, err := localCache()
+		if err != nil {
+			return err
+		}
+		entries, err := cache.Entries()
+		if err != nil {
+			return err
+		}
+		return writeCacheEntries(format, entries)
+	},
+}
+
+var cachePruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove the cached queries older than --older-than.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cache, err := localCache()
+		if err != nil {
+			return err
+		}
+		removed, err := cache.Prune(olderThan)
+		fmt.Printf("Removed %d cached queries.\n", len(removed))
+		return err
+	},
+}
+
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove every cached query.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cache, err := localCache()
+		if err != nil {
+			return err
+		}
+		removed, err := cache.Clear()
+		fmt.Printf("Removed %d cached queries.\n", len(removed))
+		return err
+	},
+}
+
+func init() {
+	addOutputFlag(cacheLsCmd)
+	cachePruneCmd.Flags().DurationVar(&olderThan, "older-than", utils.DefaultCacheTTL, "Age above which cached queries are removed")
+	cacheCmd.AddCommand(cacheLsCmd)
+	cacheCmd.AddCommand(cachePruneCmd)
+	cacheCmd.AddCommand(cacheClearCmd)
+}
+
+func localCache() (*utils.Cache, error) {
+	dir, err := utils.DefaultCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	return &utils.Cache{Dir: dir}, nil
+}
+
+var cacheKeys = []string{"key", "currency", "filter", "items", "sizeKB", "fetchedAt", "age"}
+var cacheHeaders = []string{"Key", "Currency", "Filter", "Items", "Size (KB)", "Fetched At", "Age"}
+
+func writeCacheEntries(format string, entries []utils.CacheEntry) error {
+	out, err := newRowWriter(os.Stdout, format, cacheKeys, cacheHeaders, nil)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		age := time.Since(e.FetchedAt).Round(time.Minute).String()
+		values := []any{e.Key(), e.Currency, e.Filter, e.Items, math.Round(float64(e.Size)/102.4) / 10, e.FetchedAt.Format(time.RFC3339), age}
+		if err := out.Write(values); err != nil {
+			return err
+		}
+	}
+	return out.Close()
+}
@@ -0,0 +1,191 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muandane/cloudcost/utils"
+	"github.com/spf13/cobra"
+)
+
+// priceRow is a price item along with the values computed for it by a command.
+type priceRow struct {
+	utils.Item
+	MonthlyPrice   *float64
+	Quantity       *float64
+	Usage          float64
+	LicenceSavings *float64
+	Size           *utils.VMSize
+}
+
+// column is a selectable output column of the search and calculator commands.
+// Key is the stable field name used by the structured output formats.
+type column struct {
+	Key    string
+	Header string
+	Value  func(r priceRow) any
+}
+
+var columns = []column{
+	{"armSkuName", "SKU", func(r priceRow) any { return r.ArmSkuName }},
+	{"skuName", "SKU Name", func(r priceRow) any { return r.SkuName }},
+	{"retailPrice", "Retail Price", func(r priceRow) any { return r.RetailPrice }},
+	{"unitPrice", "Unit Price", func(r priceRow) any { return r.UnitPrice }},
+	{"unitOfMeasure", "Unit of Measure", func(r priceRow) any { return r.UnitOfMeasure }},
+	{"monthlyPrice", "Monthly Price", func(r priceRow) any { return optional(r.MonthlyPrice) }},
+	{"quantity", "Quantity", func(r priceRow) any { return optional(r.Quantity) }},
+	{"usage", "Usage", func(r priceRow) any { return r.Usage }},
+	{"licenceSavings", "Licence Savings", func(r priceRow) any { return optional(r.LicenceSavings) }},
+	{"os", "OS", func(r priceRow) any { return utils.OSOf(r.Item) }},
+	{"vCPUs", "vCPUs", func(r priceRow) any { return capacity(r, utils.PerVCPU) }},
+	{"memoryGiB", "Memory GiB", func(r priceRow) any { return capacity(r, utils.PerGiB) }},
+	{"acus", "ACUs", func(r priceRow) any { return capacity(r, utils.PerACU) }},
+	{"monthlyPerVcpu", "Monthly / vCPU", func(r priceRow) any { return monthlyPer(r, utils.PerVCPU) }},
+	{"monthlyPerGib", "Monthly / GiB", func(r priceRow) any { return monthlyPer(r, utils.PerGiB) }},
+	{"monthlyPerAcu", "Monthly / ACU", func(r priceRow) any { return monthlyPer(r, utils.PerACU) }},
+	{"tierMinimumUnits", "Tier", func(r priceRow) any { return r.TierMinimumUnits }},
+	{"type", "Price Type", func(r priceRow) any { return r.Type }},
+	{"reservationTerm", "Term", func(r priceRow) any { return r.ReservationTerm }},
+	{"savingsPlan", "Savings Plan", func(r priceRow) any { return savingsPlan(r.SavingsPlan) }},
+	{"meterName", "Meter", func(r priceRow) any { return r.MeterName }},
+	{"meterId", "Meter ID", func(r priceRow) any { return r.MeterID }},
+	{"skuId", "SKU ID", func(r priceRow) any { return r.SkuID }},
+	{"productId", "Product ID", func(r priceRow) any { return r.ProductID }},
+	{"armRegionName", "Region", func(r priceRow) any { return r.ArmRegionName }},
+	{"location", "Location", func(r priceRow) any { return r.Location }},
+	{"isPrimaryMeterRegion", "Primary Region", func(r priceRow) any { return r.IsPrimaryMeterRegion }},
+	{"productName", "Product Name", func(r priceRow) any { return r.ProductName }},
+	{"serviceName", "Service", func(r priceRow) any { return r.ServiceName }},
+	{"serviceFamily", "Service Family", func(r priceRow) any { return r.ServiceFamily }},
+	{"effectiveStartDate", "Effective Date", func(r priceRow) any { return r.EffectiveStartDate }},
+	{"currencyCode", "Currency", func(r priceRow) any { return r.CurrencyCode }},
+}
+
+var searchColumns = []string{"armSkuName", "retailPrice", "unitOfMeasure", "monthlyPrice", "meterName", "os", "armRegionName", "productName"}
+var calculatorColumns = []string{"armSkuName", "retailPrice", "unitOfMeasure", "monthlyPrice", "tierMinimumUnits", "quantity", "usage", "armRegionName", "meterName", "os", "productName"}
+
+// vmSizeColumns are the columns joining the VM size catalog.
+var vmSizeColumns = []string{"vCPUs", "memoryGiB", "acus", "monthlyPerVcpu", "monthlyPerGib", "monthlyPerAcu"}
+
+// selectColumns resolves the --columns selection of cmd, falling back to the
+// command's defaults. "all" selects every column.
+func selectColumns(cmd *cobra.Command, defaults []string) ([]column, error) {
+	keys := defaults
+	if cmd.Flags().Changed("columns") {
+		keys = columnSelection
+	}
+	if len(keys) == 1 && keys[0] == "all" {
+		return columns, nil
+	}
+	selected := make([]column, 0, len(keys))
+	for _, key := range keys {
+		c, err := findColumn(key)
+		if err != nil {
+			return nil, err
+		}
+		selected = append(selected, c)
+	}
+	return selected, nil
+}
+
+func findColumn(key string) (column, error) {
+	for _, c := range columns {
+		if strings.EqualFold(c.Key, strings.TrimSpace(key)) {
+			return c, nil
+		}
+	}
+	return column{}, fmt.Errorf("unknown column %q (available: %s)", key, strings.Join(columnKeys(), ", "))
+}
+
+func columnKeys() []string {
+	keys := make([]string, len(columns))
+	for i, c := range columns {
+		keys[i] = c.Key
+	}
+	return keys
+}
+
+// optional returns nil for values that do not apply to an item.
+func optional(v *float64) any {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
+
+// monthlyPrice returns the cost of a month of a time metered item, with
+// reservations amortized over their term. Other units have no monthly price.
+func monthlyPrice(item utils.Item) *float64 {
+	monthly, ok := utils.MonthlyCost(item)
+	if !ok {
+		return nil
+	}
+	return &monthly
+}
+
+// capacity returns the vCPUs, GiB or ACUs of the VM size of r, if known.
+func capacity(r priceRow, per string) any {
+	if r.Size == nil {
+		return nil
+	}
+	if c, ok := r.Size.Capacity(per); ok {
+		return c
+	}
+	return nil
+}
+
+// monthlyPer returns the monthly price of r normalized by the capacity of its
+// VM size, if known.
+func monthlyPer(r priceRow, per string) any {
+	c, ok := capacity(r, per).(float64)
+	if !ok || r.MonthlyPrice == nil {
+		return nil
+	}
+	return *r.MonthlyPrice / c
+}
+
+// savingsPlan prints as "term: price" pairs in the text formats.
+type savingsPlan []utils.SavingsPlanTerm
+
+func (s savingsPlan) String() string {
+	parts := make([]string, len(s))
+	for i, t := range s {
+		parts[i] = fmt.Sprintf("%s: %s", t.Term, formatCell(t.RetailPrice, ""))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// newPriceWriter returns a writer for the selected columns, coloring the meter
+// column by Spot and Low Priority meters in tables.
+func newPriceWriter(format string, cols []column) (rowWriter, error) {
+	keys := make([]string, len(cols))
+	headers := make([]string, len(cols))
+	for i, c := range cols {
+		keys[i] = c.Key
+		headers[i] = c.Header
+	}
+	return newRowWriter(os.Stdout, format, keys, headers, func(values []any, col int) lipgloss.TerminalColor {
+		if cols[col].Key != "meterName" {
+			return nil
+		}
+		// Check if the "Meter" column contains "Spot" or "Low"
+		meter, _ := values[col].(string)
+		if strings.Contains(meter, "Spot") {
+			return typeColors.Spot
+		} else if strings.Contains(meter, "Low") {
+			return typeColors.Low
+		}
+		return typeColors.Normal
+	})
+}
+
+// writePriceRow writes the selected columns of r.
+func writePriceRow(out rowWriter, cols []column, r priceRow) error {
+	values := make([]any, len(cols))
+	for i, c := range cols {
+		values[i] = c.Value(r)
+	}
+	return out.Write(values)
+}
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/muandane/cloudcost/utils"
+	"github.com/spf13/cobra"
+)
+
+var whereFilters []string
+var containsFilters []string
+var startsWithFilters []string
+
+// addFilterFlags registers the repeatable field filters on cmd.
+func addFilterFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVar(&whereFilters, "where", nil, "Filter on field=value, repeatable (e.g., 'serviceFamily=Compute')")
+	cmd.Flags().StringArrayVar(&containsFilters, "contains", nil, "Filter on fields containing field=value, repeatable (e.g., 'productName=Windows')")
+	cmd.Flags().StringArrayVar(&startsWithFilters, "starts-with", nil, "Filter on fields starting with field=value, repeatable (e.g., 'meterName=D4')")
+}
+
+// buildFilter combines the search flags with the --where, --contains and
+// --starts-with filters. Filters on the same field are or'ed together, filters
+// on different fields are and'ed.
+func buildFilter(cmd *cobra.Command) (utils.Filter, error) {
+	if region == "" && service == "" && vmType == "" && len(whereFilters)+len(containsFilters)+len(startsWithFilters) == 0 {
+		return nil, errors.New("no filter given, use --region, --service, --type, --where, --contains or --starts-with")
+	}
+	return flagFilter(cmd)
+}
+
+// flagFilter is buildFilter for commands where no filter means the whole
+// catalog. The --pricing-type default only applies to commands that have it.
+func flagFilter(cmd *cobra.Command) (utils.Filter, error) {
+	var fields []string
+	byField := map[string][]utils.Filter{}
+	add := func(specs []string, build func(field, value string) utils.Filter) error {
+		for _, spec := range specs {
+			name, value, ok := strings.Cut(spec, "=")
+			if !ok {
+				return fmt.Errorf("invalid filter %q, expected field=value", spec)
+			}
+			field, err := utils.FilterField(name)
+			if err != nil {
+				return err
+			}
+			if _, seen := byField[field]; !seen {
+				fields = append(fields, field)
+			}
+			byField[field] = append(byField[field], build(field, value))
+		}
+		return nil
+	}
+	if err := add(whereFilters, utils.Eq); err != nil {
+		return nil, err
+	}
+	if err := add(containsFilters, utils.Contains); err != nil {
+		return nil, err
+	}
+	if err := add(startsWithFilters, utils.StartsWith); err != nil {
+		return nil, err
+	}
+
+	// An explicit priceType filter replaces the default --pricing-type.
+	priceType := pricingType
+	if _, ok := byField["priceType"]; (ok || cmd.Flags().Lookup("pricing-type") == nil) && !cmd.Flags().Changed("pricing-type") {
+		priceType = ""
+	}
+	filters := []utils.Filter{utils.Query(region, service, vmType, priceType)}
+	if cmd.Flags().Lookup("os") != nil {
+		osQuery, err := osFilter()
+		if err != nil {
+			return nil, err
+		}
+		if osQuery != nil {
+			filters = append(filters, osQuery)
+		}
+	}
+	for _, field := range fields {
+		filters = append(filters, utils.Or(byField[field]...))
+	}
+	return utils.And(filters...), nil
+}
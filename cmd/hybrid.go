@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/muandane/cloudcost/utils"
+	"github.com/spf13/cobra"
+)
+
+var osName string
+var hybridBenefit bool
+
+// addOSFlags registers the --os and --hybrid-benefit flags of the VM pricing
+// commands.
+func addOSFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&osName, "os", "", "Only show VM prices for this operating system: linux or windows")
+	cmd.Flags().BoolVar(&hybridBenefit, "hybrid-benefit", false, "Price Windows VMs with Azure Hybrid Benefit, at the Linux rate, and show the licence savings")
+}
+
+// osFilter narrows the query to the VM prices of --os. Linux prices are told
+// apart from Windows ones once fetched, by matchOS.
+func osFilter() (utils.Filter, error) {
+	switch strings.ToLower(osName) {
+	case "":
+		return nil, nil
+	case "linux":
+		return utils.Eq("serviceName", "Virtual Machines"), nil
+	case "windows":
+		return utils.And(utils.Eq("serviceName", "Virtual Machines"), utils.Contains("productName", utils.Windows)), nil
+	}
+	return nil, fmt.Errorf("unknown operating system %q, expected linux or windows", osName)
+}
+
+func matchOS(item utils.Item) bool {
+	return osName == "" || strings.EqualFold(utils.OSOf(item), osName)
+}
+
+// hybridColumns adds the licence savings after the monthly price of the
+// default columns when --hybrid-benefit is set.
+func hybridColumns(defaults []string) []string {
+	if !hybridBenefit {
+		return defaults
+	}
+	i := slices.Index(defaults, "monthlyPrice") + 1
+	return slices.Insert(slices.Clone(defaults), i, "licenceSavings")
+}
+
+// eachPriceRow calls fn with a row per item of query for the --os system.
+// With --hybrid-benefit, Windows VM prices are replaced by the Linux price of
+// the same meter, looked up when the query did not return it, and the rows
+// hold the licence savings per month.
+func eachPriceRow(cmd *cobra.Command, query utils.Filter, fn func(priceRow) error) error {
+	if !hybridBenefit {
+		return eachItem(cmd, query, func(item utils.Item) error {
+			if !matchOS(item) {
+				return nil
+			}
+			return fn(priceRow{Item: item, MonthlyPrice: monthlyPrice(item)})
+		})
+	}
+
+	var items []utils.Item
+	linux := map[string]utils.Item{}
+	err := eachItem(cmd, query, func(item utils.Item) error {
+		if utils.OSOf(item) == utils.Linux {
+			linux[utils.LicenceKey(item)] = item
+		}
+		if matchOS(item) {
+			items = append(items, item)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := lookupLinuxPrices(cmd, items, linux); err != nil {
+		return err
+	}
+	for _, item := range items {
+		r := priceRow{Item: item}
+		if l, ok := linux[utils.LicenceKey(item)]; ok && utils.OSOf(item) == utils.Windows {
+			r.RetailPrice, r.UnitPrice, r.SavingsPlan = l.RetailPrice, l.UnitPrice, l.SavingsPlan
+			windowsCost, ok := utils.MonthlyCost(item)
+			linuxCost, ok2 := utils.MonthlyCost(l)
+			if ok && ok2 {
+				savings := windowsCost - linuxCost
+				r.LicenceSavings = &savings
+			}
+		}
+		r.MonthlyPrice = monthlyPrice(r.Item)
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lookupLinuxPrices adds to linux the prices of the VM sizes and regions of
+// the Windows items it misses.
+func lookupLinuxPrices(cmd *cobra.Command, items []utils.Item, linux map[string]utils.Item) error {
+	var skus, regions []string
+	for _, item := range items {
+		if _, ok := linux[utils.LicenceKey(item)]; ok || utils.OSOf(item) != utils.Windows {
+			continue
+		}
+		if !slices.Contains(skus, item.ArmSkuName) {
+			skus = append(skus, item.ArmSkuName)
+		}
+		if !slices.Contains(regions, item.ArmRegionName) {
+			regions = append(regions, item.ArmRegionName)
+		}
+	}
+	if len(skus) == 0 {
+		return nil
+	}
+	var skuFilters, regionFilters []utils.Filter
+	for _, sku := range skus {
+		skuFilters = append(skuFilters, utils.Eq("armSkuName", sku))
+	}
+	for _, r := range regions {
+		regionFilters = append(regionFilters, utils.Eq("armRegionName", r))
+	}
+	query := utils.And(utils.Eq("serviceName", "Virtual Machines"), utils.Or(skuFilters...), utils.Or(regionFilters...))
+	return eachItem(cmd, query, func(item utils.Item) error {
+		if utils.OSOf(item) == utils.Linux {
+			linux[utils.LicenceKey(item)] = item
+		}
+		return nil
+	})
+}
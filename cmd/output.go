@@ -0,0 +1,340 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/lipgloss/table"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var outputFormat string
+
+var outputFormats = []string{"table", "json", "jsonl", "csv", "tsv", "markdown", "yaml"}
+
+// addOutputFlag registers --output on cmd.
+func addOutputFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&outputFormat, "output", "o", "", "Output format: "+strings.Join(outputFormats, ", ")+" (default table, or tsv when stdout is not a terminal)")
+}
+
+// resolveOutputFormat validates --output, picking a plain format when stdout
+// is not a terminal and no format was given.
+func resolveOutputFormat() (string, error) {
+	if outputFormat == "" {
+		if stdoutIsTerminal() {
+			return "table", nil
+		}
+		return "tsv", nil
+	}
+	for _, f := range outputFormats {
+		if strings.EqualFold(f, outputFormat) {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unknown output format %q (available: %s)", outputFormat, strings.Join(outputFormats, ", "))
+}
+
+func stdoutIsTerminal() bool {
+	return isTerminal(os.Stdout)
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// rowWriter writes the records of a command in one output format. Values are
+// given in the same order as the keys the writer was created with.
+type rowWriter interface {
+	Write(values []any) error
+	Close() error
+}
+
+// cellColor picks the foreground of a table cell, returning nil for the default.
+type cellColor func(values []any, col int) lipgloss.TerminalColor
+
+// newRowWriter returns a writer for format. keys are the stable field names
+// used by the machine-readable formats, headers the titles used by tables and
+// markdown.
+func newRowWriter(w io.Writer, format string, keys, headers []string, color cellColor) (rowWriter, error) {
+	switch format {
+	case "table":
+		return &tableWriter{w: w, headers: headers, color: color}, nil
+	case "json":
+		return &jsonWriter{w: w, keys: keys}, nil
+	case "jsonl":
+		return &jsonWriter{w: w, keys: keys, lines: true}, nil
+	case "csv":
+		return newCSVWriter(w, keys)
+	case "tsv":
+		return newTSVWriter(w, keys)
+	case "markdown":
+		return newMarkdownWriter(w, headers)
+	case "yaml":
+		return &yamlWriter{w: w, keys: keys}, nil
+	}
+	return nil, fmt.Errorf("unknown output format %q", format)
+}
+
+// formatCell renders a value for the text formats. nil means "not applicable".
+func formatCell(v any, empty string) string {
+	switch v := v.(type) {
+	case nil:
+		return empty
+	case float64:
+		return strconv.FormatFloat(math.Round(v*1e6)/1e6, 'f', -1, 64)
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+		return v.Format("2006-01-02")
+	case fmt.Stringer:
+		return v.String()
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// lessCell orders numbers numerically and anything else as text, empty cells
+// last.
+func lessCell(a, b any) bool {
+	switch {
+	case b == nil:
+		return a != nil
+	case a == nil:
+		return false
+	}
+	x, ok := a.(float64)
+	y, ok2 := b.(float64)
+	if ok && ok2 {
+		return x < y
+	}
+	return strings.ToLower(formatCell(a, "")) < strings.ToLower(formatCell(b, ""))
+}
+
+type tableWriter struct {
+	w       io.Writer
+	headers []string
+	color   cellColor
+	rows    [][]any
+}
+
+func (t *tableWriter) Write(values []any) error {
+	t.rows = append(t.rows, values)
+	return nil
+}
+
+func (t *tableWriter) Close() error {
+	re := lipgloss.NewRenderer(t.w)
+	baseStyle := re.NewStyle().Padding(0, 1)
+	headerStyle := baseStyle.Copy().Foreground(lipgloss.AdaptiveColor{Light: "#186F65", Dark: "#1AACAC"}).Bold(true)
+
+	headers := make([]string, len(t.headers))
+	for i, h := range t.headers {
+		headers[i] = strings.ToUpper(h)
+	}
+	tableData := make([][]string, len(t.rows))
+	for i, row := range t.rows {
+		tableData[i] = make([]string, len(row))
+		for j, v := range row {
+			tableData[i][j] = formatCell(v, "---")
+		}
+	}
+
+	tbl := table.New().
+		Border(lipgloss.NormalBorder()).
+		BorderStyle(re.NewStyle().Foreground(lipgloss.AdaptiveColor{Light: "#186F65", Dark: "#1AACAC"})).
+		Headers(headers...).
+		Rows(tableData...).
+		StyleFunc(func(row, col int) lipgloss.Style {
+			if row == 0 {
+				return headerStyle
+			}
+			if t.color != nil {
+				if color := t.color(t.rows[row-1], col); color != nil {
+					return baseStyle.Copy().Foreground(color)
+				}
+			}
+			return baseStyle.Copy().Foreground(lipgloss.AdaptiveColor{Light: "#053B50", Dark: "#F1EFEF"})
+		})
+	_, err := fmt.Fprintln(t.w, tbl)
+	return err
+}
+
+// record marshals values as a JSON object keeping the column order.
+type record struct {
+	keys   []string
+	values []any
+}
+
+func (r record) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range r.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(r.values[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// jsonWriter streams records either as a JSON array or as JSON lines.
+type jsonWriter struct {
+	w     io.Writer
+	keys  []string
+	lines bool
+	count int
+}
+
+func (j *jsonWriter) Write(values []any) error {
+	data, err := json.Marshal(record{keys: j.keys, values: values})
+	if err != nil {
+		return err
+	}
+	switch {
+	case j.lines:
+		_, err = fmt.Fprintf(j.w, "%s\n", data)
+	case j.count == 0:
+		_, err = fmt.Fprintf(j.w, "[\n  %s", data)
+	default:
+		_, err = fmt.Fprintf(j.w, ",\n  %s", data)
+	}
+	j.count++
+	return err
+}
+
+func (j *jsonWriter) Close() error {
+	if j.lines {
+		return nil
+	}
+	var err error
+	if j.count == 0 {
+		_, err = fmt.Fprintln(j.w, "[]")
+	} else {
+		_, err = fmt.Fprintln(j.w, "\n]")
+	}
+	return err
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func newCSVWriter(w io.Writer, headers []string) (rowWriter, error) {
+	c := &csvWriter{w: csv.NewWriter(w)}
+	return c, c.w.Write(headers)
+}
+
+func (c *csvWriter) Write(values []any) error {
+	cells := make([]string, len(values))
+	for i, v := range values {
+		cells[i] = formatCell(v, "")
+	}
+	return c.w.Write(cells)
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// delimitedWriter writes one line per record with cells joined by sep. Values
+// are stripped of the separator and line breaks rather than quoted.
+type delimitedWriter struct {
+	w      io.Writer
+	sep    string
+	prefix string
+	suffix string
+	escape func(string) string
+}
+
+func (d *delimitedWriter) line(cells []string) error {
+	for i, cell := range cells {
+		cells[i] = d.escape(cell)
+	}
+	_, err := fmt.Fprintln(d.w, d.prefix+strings.Join(cells, d.sep)+d.suffix)
+	return err
+}
+
+func (d *delimitedWriter) Write(values []any) error {
+	cells := make([]string, len(values))
+	for i, v := range values {
+		cells[i] = formatCell(v, "")
+	}
+	return d.line(cells)
+}
+
+func (d *delimitedWriter) Close() error {
+	return nil
+}
+
+func newTSVWriter(w io.Writer, headers []string) (rowWriter, error) {
+	d := &delimitedWriter{w: w, sep: "\t", escape: strings.NewReplacer("\t", " ", "\n", " ", "\r", " ").Replace}
+	return d, d.line(append([]string(nil), headers...))
+}
+
+func newMarkdownWriter(w io.Writer, headers []string) (rowWriter, error) {
+	d := &delimitedWriter{w: w, sep: " | ", prefix: "| ", suffix: " |", escape: strings.NewReplacer("|", `\|`, "\n", " ", "\r", " ").Replace}
+	if err := d.line(append([]string(nil), headers...)); err != nil {
+		return nil, err
+	}
+	rule := make([]string, len(headers))
+	for i := range rule {
+		rule[i] = "---"
+	}
+	return d, d.line(rule)
+}
+
+// yamlWriter streams records as the items of a YAML sequence.
+type yamlWriter struct {
+	w     io.Writer
+	keys  []string
+	count int
+}
+
+func (y *yamlWriter) Write(values []any) error {
+	item := &yaml.Node{Kind: yaml.MappingNode}
+	for i, key := range y.keys {
+		var value yaml.Node
+		if err := value.Encode(values[i]); err != nil {
+			return err
+		}
+		item.Content = append(item.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, &value)
+	}
+	data, err := yaml.Marshal(&yaml.Node{Kind: yaml.SequenceNode, Content: []*yaml.Node{item}})
+	if err != nil {
+		return err
+	}
+	y.count++
+	_, err = y.w.Write(data)
+	return err
+}
+
+func (y *yamlWriter) Close() error {
+	if y.count == 0 {
+		_, err := fmt.Fprintln(y.w, "[]")
+		return err
+	}
+	return nil
+}
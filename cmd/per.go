@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/muandane/cloudcost/utils"
+	"github.com/spf13/cobra"
+)
+
+var perUnit string
+
+// perLabels are the key suffixes and headers of the normalized price columns.
+var perLabels = map[string][2]string{
+	utils.PerVCPU: {"Vcpu", "vCPU"},
+	utils.PerGiB:  {"Gib", "GiB"},
+	utils.PerACU:  {"Acu", "ACU"},
+}
+
+// perCapacityColumns are the capacity columns, by --per unit.
+var perCapacityColumns = map[string]string{
+	utils.PerVCPU: "vCPUs",
+	utils.PerGiB:  "memoryGiB",
+	utils.PerACU:  "acus",
+}
+
+// addPerFlag registers --per on cmd.
+func addPerFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&perUnit, "per", "", "Normalize VM prices per vcpu, gib or acu, from the VM size catalog")
+}
+
+// perColumns adds the capacity and the normalized monthly price after the
+// monthly price of the default columns when --per is set, as checked by
+// loadPerCatalog.
+func perColumns(defaults []string) []string {
+	if perUnit == "" {
+		return defaults
+	}
+	i := slices.Index(defaults, "monthlyPrice") + 1
+	return slices.Insert(slices.Clone(defaults), i, perCapacityColumns[perUnit], "monthlyPer"+perLabels[perUnit][0])
+}
+
+// loadPerCatalog checks --per and loads the VM size catalog when it is set.
+func loadPerCatalog() (*utils.VMCatalog, error) {
+	if perUnit == "" {
+		return nil, nil
+	}
+	perUnit = strings.ToLower(perUnit)
+	if _, ok := perLabels[perUnit]; !ok {
+		return nil, fmt.Errorf("unknown --per %q (available: %s)", perUnit, strings.Join(utils.PerUnits, ", "))
+	}
+	return loadVMCatalog()
+}
+
+// loadVMCatalog reads the imported VM size catalog, or the bundled one.
+func loadVMCatalog() (*utils.VMCatalog, error) {
+	path, err := vmCatalogPath()
+	if err != nil {
+		return nil, err
+	}
+	return utils.LoadVMCatalog(path)
+}
+
+// normalizePriceTable divides the costs of table by the --per capacity of the
+// VM size of each row, as found in catalog, and adds the capacity before the
+// price columns of layout. Rows of sizes missing from the catalog are left
+// out and reported on stderr.
+func normalizePriceTable(table *utils.PriceTable, layout priceTableLayout, catalog *utils.VMCatalog) (*utils.PriceTable, priceTableLayout) {
+	var missing []string
+	capacity := func(row string) (float64, bool) {
+		sku := table.Item(row).ArmSkuName
+		size, ok := catalog.Lookup(sku)
+		if !ok {
+			missing = append(missing, sku)
+			return 0, false
+		}
+		return size.Capacity(perUnit)
+	}
+	normalized := table.Normalize(capacity)
+	if len(missing) > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: %s not in the VM size catalog, see 'azure vm import'\n", strings.Join(missing, ", "))
+	}
+
+	layout.Per = perUnit
+	if slices.Contains(layout.Keys, perCapacityColumns[perUnit]) {
+		return normalized, layout
+	}
+	lead := layout.Lead
+	layout.Keys = append(slices.Clone(layout.Keys), perCapacityColumns[perUnit])
+	layout.Headers = append(slices.Clone(layout.Headers), perHeader())
+	layout.Lead = func(row string) []any {
+		size, _ := catalog.Lookup(table.Item(row).ArmSkuName)
+		c, _ := size.Capacity(perUnit)
+		return append(lead(row), c)
+	}
+	return normalized, layout
+}
+
+// perHeader is the header of the capacity column of --per.
+func perHeader() string {
+	switch perUnit {
+	case utils.PerVCPU:
+		return "vCPUs"
+	case utils.PerGiB:
+		return "Memory GiB"
+	}
+	return "ACUs"
+}
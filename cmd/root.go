@@ -4,8 +4,11 @@ Root CMD for Cloud Costs CLI
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 
 	"github.com/spf13/cobra"
 )
@@ -13,17 +16,32 @@ import (
 func init() {
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(azureCmd)
+	rootCmd.AddCommand(cacheCmd)
 }
 
 var rootCmd = &cobra.Command{
 	Use:   "cloudcost",
 	Short: "Cloud Costs CLI",
 	Long:  `cloudcost is a Go CLI that retrieves pricing information for Azure services using the Azure pricing API.`,
+	// Errors are printed once by Execute, without the usage that would bury them.
+	SilenceErrors: true,
+	SilenceUsage:  true,
 }
 
+// Execute runs the CLI and exits with a non-zero code on failure: 2 when over
+// budget, 3 when prices could not be looked up, 4 when resources could not be
+// priced and 1 for any other error.
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	err := rootCmd.ExecuteContext(ctx)
+	stop()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		code := 1
+		var exit *exitError
+		if errors.As(err, &exit) {
+			code = exit.code
+		}
+		os.Exit(code)
 	}
 }
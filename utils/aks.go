@@ -0,0 +1,261 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The control plane tiers of an AKS cluster.
+const (
+	AKSFree     = "Free"
+	AKSStandard = "Standard"
+	AKSPremium  = "Premium"
+)
+
+// AKSCluster describes an AKS cluster to price.
+type AKSCluster struct {
+	Currency string `yaml:"currency"`
+	Region   string `yaml:"region"`
+	// Tier is the control plane tier, Free when empty.
+	Tier      string        `yaml:"tier"`
+	NodePools []AKSNodePool `yaml:"nodePools"`
+	// LoadBalancer is the SKU of the cluster load balancer: Standard when
+	// empty, Basic or None. Basic load balancers are free.
+	LoadBalancer string `yaml:"loadBalancer"`
+	// PublicIPs is the number of Standard public IPs, outbound and ingress.
+	PublicIPs int `yaml:"publicIPs"`
+	// EgressGB is the monthly data sent to the internet, also billed as data
+	// processed by a Standard load balancer.
+	EgressGB float64 `yaml:"egressGB"`
+}
+
+// AKSNodePool is a node pool of fixed size, or autoscaled between MinCount
+// and MaxCount when Count is not set.
+type AKSNodePool struct {
+	Name     string `yaml:"name"`
+	VMSize   string `yaml:"vmSize"`
+	Count    *int   `yaml:"count"`
+	MinCount int    `yaml:"minCount"`
+	MaxCount int    `yaml:"maxCount"`
+	// OSType is Linux when empty, or Windows.
+	OSType string `yaml:"osType"`
+	Spot   bool   `yaml:"spot"`
+	// OSDiskType is Managed when empty, or Ephemeral which is not billed.
+	OSDiskType string `yaml:"osDiskType"`
+	// OSDiskStorage is the storage type of managed OS disks, Premium_LRS when empty.
+	OSDiskStorage string  `yaml:"osDiskStorage"`
+	OSDiskSizeGB  float64 `yaml:"osDiskSizeGB"`
+}
+
+// AKSComponent is a resource of a cluster with its quantity at the minimum
+// and maximum size of the node pools.
+type AKSComponent struct {
+	Resource Resource
+	Min      float64
+	Max      float64
+}
+
+// AKSLine is a priced component. Line holds the cost at the maximum size.
+type AKSLine struct {
+	Line
+	Min        float64
+	Max        float64
+	MinMonthly float64
+}
+
+// LoadAKSCluster reads a YAML or JSON cluster file.
+func LoadAKSCluster(path string) (*AKSCluster, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c AKSCluster
+	if err := yaml.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &c, nil
+}
+
+// ParseAKSNodePool reads a node pool from comma separated key=value pairs,
+// e.g. "name=system,vmSize=Standard_D4s_v5,count=3". Keys are the YAML
+// field names, matched ignoring case.
+func ParseAKSNodePool(s string) (AKSNodePool, error) {
+	var p AKSNodePool
+	for _, pair := range strings.Split(s, ",") {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return p, fmt.Errorf("node pool %q: expected key=value, got %q", s, pair)
+		}
+		var err error
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "name":
+			p.Name = value
+		case "vmsize", "size":
+			p.VMSize = value
+		case "count":
+			var count int
+			count, err = strconv.Atoi(value)
+			p.Count = &count
+		case "mincount", "min":
+			p.MinCount, err = strconv.Atoi(value)
+		case "maxcount", "max":
+			p.MaxCount, err = strconv.Atoi(value)
+		case "ostype", "os":
+			p.OSType = value
+		case "spot":
+			p.Spot, err = strconv.ParseBool(value)
+		case "osdisktype":
+			p.OSDiskType = value
+		case "osdiskstorage":
+			p.OSDiskStorage = value
+		case "osdisksizegb", "osdisksize":
+			p.OSDiskSizeGB, err = strconv.ParseFloat(value, 64)
+		default:
+			return p, fmt.Errorf("node pool %q: unknown key %q", s, key)
+		}
+		if err != nil {
+			return p, fmt.Errorf("node pool %q: %s: %w", s, key, err)
+		}
+	}
+	return p, nil
+}
+
+// Nodes returns the minimum and maximum node count of the pool.
+func (p AKSNodePool) Nodes() (int, int, error) {
+	if p.Count != nil {
+		if p.MinCount != 0 || p.MaxCount != 0 {
+			return 0, 0, fmt.Errorf("node pool %s: set either count or minCount and maxCount", p.Name)
+		}
+		if *p.Count < 0 {
+			return 0, 0, fmt.Errorf("node pool %s: negative count", p.Name)
+		}
+		return *p.Count, *p.Count, nil
+	}
+	if p.MaxCount == 0 {
+		return 0, 0, fmt.Errorf("node pool %s: set count, or minCount and maxCount for autoscaling", p.Name)
+	}
+	if p.MinCount < 0 || p.MinCount > p.MaxCount {
+		return 0, 0, fmt.Errorf("node pool %s: minCount %d is not between 0 and maxCount %d", p.Name, p.MinCount, p.MaxCount)
+	}
+	return p.MinCount, p.MaxCount, nil
+}
+
+// Autoscaled reports whether a node pool has autoscaling bounds.
+func (c *AKSCluster) Autoscaled() bool {
+	for _, p := range c.NodePools {
+		if low, high, err := p.Nodes(); err == nil && low != high {
+			return true
+		}
+	}
+	return false
+}
+
+// Components lists the priced resources of the cluster: the control plane,
+// the nodes and OS disks of every pool, the load balancer, the public IPs
+// and the egress.
+func (c *AKSCluster) Components() ([]AKSComponent, error) {
+	if len(c.NodePools) == 0 {
+		return nil, fmt.Errorf("the cluster has no node pool")
+	}
+	var components []AKSComponent
+	add := func(r Resource, low, high float64) {
+		components = append(components, AKSComponent{Resource: r, Min: low, Max: high})
+	}
+
+	switch {
+	case c.Tier == "" || strings.EqualFold(c.Tier, AKSFree):
+	case strings.EqualFold(c.Tier, AKSStandard), strings.EqualFold(c.Tier, AKSPremium):
+		add(Resource{Name: "control-plane", Service: "Azure Kubernetes Service", SKU: capitalize(c.Tier), Region: c.Region}, 1, 1)
+	default:
+		return nil, fmt.Errorf("unknown tier %q, expected Free, Standard or Premium", c.Tier)
+	}
+
+	for _, p := range c.NodePools {
+		if p.Name == "" || p.VMSize == "" {
+			return nil, fmt.Errorf("node pools need a name and a vmSize")
+		}
+		low, high, err := p.Nodes()
+		if err != nil {
+			return nil, err
+		}
+		var windows bool
+		switch strings.ToLower(p.OSType) {
+		case "", "linux":
+		case "windows":
+			windows = true
+		default:
+			return nil, fmt.Errorf("node pool %s: unknown osType %q, expected Linux or Windows", p.Name, p.OSType)
+		}
+		nodes := VirtualMachine(p.Name+"/nodes", p.VMSize, c.Region, windows, 0)
+		if p.Spot {
+			nodes.Meter = "Spot"
+		}
+		add(nodes, float64(low), float64(high))
+
+		switch strings.ToLower(p.OSDiskType) {
+		case "", "managed":
+			storage, size := p.OSDiskStorage, p.OSDiskSizeGB
+			if storage == "" {
+				storage = "Premium_LRS"
+			}
+			if size == 0 {
+				size = 128
+			}
+			disk, err := ManagedDisk(p.Name+"/os-disks", storage, size, c.Region)
+			if err != nil {
+				return nil, fmt.Errorf("node pool %s: %w", p.Name, err)
+			}
+			add(disk, float64(low), float64(high))
+		case "ephemeral":
+		default:
+			return nil, fmt.Errorf("node pool %s: unknown osDiskType %q, expected Managed or Ephemeral", p.Name, p.OSDiskType)
+		}
+	}
+
+	switch {
+	case c.LoadBalancer == "" || strings.EqualFold(c.LoadBalancer, "Standard"):
+		add(Resource{Name: "load-balancer", Service: "Load Balancer", SKU: "Standard", Meter: "Included LB Rules", Region: c.Region}, 1, 1)
+		if c.EgressGB > 0 {
+			add(Resource{Name: "load-balancer/data", Service: "Load Balancer", SKU: "Standard", Meter: "Data Processed", Region: c.Region, Usage: Usage{GB: c.EgressGB}}, 1, 1)
+		}
+	case strings.EqualFold(c.LoadBalancer, "Basic"), strings.EqualFold(c.LoadBalancer, "None"):
+	default:
+		return nil, fmt.Errorf("unknown load balancer %q, expected Standard, Basic or None", c.LoadBalancer)
+	}
+	if c.PublicIPs > 0 {
+		add(PublicIP("public-ips", "Standard", "Static", c.Region), float64(c.PublicIPs), float64(c.PublicIPs))
+	}
+	if c.EgressGB > 0 {
+		add(Bandwidth("egress", c.EgressGB, c.Region), 1, 1)
+	}
+	return components, nil
+}
+
+// EstimateAKS prices the components of a cluster once per unit, then at the
+// minimum and maximum size of its node pools.
+func (e *Estimator) EstimateAKS(ctx context.Context, components []AKSComponent) ([]AKSLine, error) {
+	resources := make([]Resource, len(components))
+	for i, component := range components {
+		one := 1.0
+		resources[i] = component.Resource
+		resources[i].Quantity = &one
+	}
+	lines, err := e.Estimate(ctx, resources)
+	if err != nil {
+		return nil, err
+	}
+	aks := make([]AKSLine, len(lines))
+	for i, line := range lines {
+		component := components[i]
+		unit := line.Monthly
+		line.Resource.Quantity = &component.Max
+		line.Monthly = unit * component.Max
+		aks[i] = AKSLine{Line: line, Min: component.Min, Max: component.Max, MinMonthly: unit * component.Min}
+	}
+	return aks, nil
+}
@@ -0,0 +1,911 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Template is an ARM deployment template, as written by hand or compiled
+// from Bicep.
+type Template struct {
+	Parameters map[string]struct {
+		Type         string `json:"type"`
+		DefaultValue any    `json:"defaultValue"`
+	} `json:"parameters"`
+	Variables map[string]any `json:"variables"`
+	// Resources is an array, or an object keyed by symbolic name for
+	// templates using languageVersion 2.0.
+	Resources json.RawMessage `json:"resources"`
+}
+
+// LoadTemplate reads an ARM template.
+func LoadTemplate(path string) (*Template, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var template Template
+	if err := json.Unmarshal(data, &template); err != nil {
+		return nil, fmt.Errorf("%s is not an ARM template: %w", path, err)
+	}
+	return &template, nil
+}
+
+// LoadParameters reads the values of a deployment parameters file.
+func LoadParameters(path string) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Parameters map[string]struct {
+			Value any `json:"value"`
+		} `json:"parameters"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%s is not a parameters file: %w", path, err)
+	}
+	values := make(map[string]any, len(file.Parameters))
+	for name, p := range file.Parameters {
+		values[name] = p.Value
+	}
+	return values, nil
+}
+
+// ArmOptions hold the deployment values a template can reference.
+type ArmOptions struct {
+	// Location is the location of the target resource group.
+	Location string
+	// StorageGB is the capacity assumed for storage accounts.
+	StorageGB float64
+}
+
+// ArmResource is a resource instance of a template and the lines pricing it.
+type ArmResource struct {
+	Name      string
+	Type      string
+	Resources []Resource
+	// Err is set when the resource could not be resolved or is not supported.
+	Err error
+}
+
+// ArmResources evaluates the template with the given parameter values and
+// maps each resource instance, expanding copy loops, to estimate lines.
+func ArmResources(template *Template, parameters map[string]any, opts ArmOptions) ([]ArmResource, error) {
+	ctx := &armContext{
+		template:   template,
+		parameters: map[string]any{},
+		variables:  map[string]any{},
+		resolving:  map[string]bool{},
+		location:   opts.Location,
+	}
+	// Parameter names are matched ignoring case, like ARM does.
+	for name, v := range parameters {
+		declared := false
+		for declaredName := range template.Parameters {
+			declared = declared || strings.EqualFold(declaredName, name)
+		}
+		if !declared {
+			return nil, fmt.Errorf("parameter %q is not declared by the template", name)
+		}
+		ctx.parameters[strings.ToLower(name)] = v
+	}
+
+	definitions, err := templateResources(template.Resources)
+	if err != nil {
+		return nil, err
+	}
+	var resources []ArmResource
+	for _, definition := range definitions {
+		resources = append(resources, ctx.expand(definition, opts)...)
+	}
+	return resources, nil
+}
+
+// EstimateTemplate prices the resources of a template. Resources that could
+// not be resolved or are not supported are returned as a line with Err set.
+func (e *Estimator) EstimateTemplate(ctx context.Context, resources []ArmResource) ([]Line, error) {
+	var lines []Line
+	for _, r := range resources {
+		if r.Err != nil {
+			lines = append(lines, Line{Resource: Resource{Name: r.Name}, Err: r.Err})
+			continue
+		}
+		priced, err := e.Estimate(ctx, r.Resources)
+		lines = append(lines, priced...)
+		if err != nil {
+			return lines, err
+		}
+	}
+	return lines, nil
+}
+
+// templateResources flattens the resources of a template, including the
+// resources nested in a parent resource.
+func templateResources(raw json.RawMessage) ([]map[string]any, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	var list []map[string]any
+	if err := json.Unmarshal(raw, &list); err != nil {
+		var symbolic map[string]map[string]any
+		if err := json.Unmarshal(raw, &symbolic); err != nil {
+			return nil, fmt.Errorf("invalid template resources: %w", err)
+		}
+		names := make([]string, 0, len(symbolic))
+		for name := range symbolic {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			list = append(list, symbolic[name])
+		}
+	}
+	var flat []map[string]any
+	for _, r := range list {
+		flat = append(flat, r)
+		if nested, ok := r["resources"].([]any); ok {
+			data, _ := json.Marshal(nested)
+			children, err := templateResources(data)
+			if err != nil {
+				return nil, err
+			}
+			for _, child := range children {
+				// Nested types are relative to their parent.
+				if t, _ := child["type"].(string); !strings.Contains(t, ".") {
+					child["type"] = fmt.Sprint(r["type"]) + "/" + t
+				}
+				flat = append(flat, child)
+			}
+		}
+	}
+	return flat, nil
+}
+
+// expand evaluates every copy of a resource definition.
+func (c *armContext) expand(definition map[string]any, opts ArmOptions) []ArmResource {
+	resourceType, _ := definition["type"].(string)
+	if existing, _ := definition["existing"].(bool); existing || freeArmTypes[strings.ToLower(resourceType)] {
+		return nil
+	}
+	count := 1
+	if loop, ok := definition["copy"].(map[string]any); ok {
+		n, err := c.eval(loop["count"])
+		if err != nil {
+			return []ArmResource{{Name: resourceType, Type: resourceType, Err: err}}
+		}
+		if count, err = toInt(n); err != nil {
+			return []ArmResource{{Name: resourceType, Type: resourceType, Err: fmt.Errorf("copy count: %w", err)}}
+		}
+	}
+
+	var resources []ArmResource
+	for i := 0; i < count; i++ {
+		c.copyIndex = i
+		// The name is only displayed, the raw expression will do when it
+		// cannot be evaluated.
+		resourceName, err := c.eval(definition["name"])
+		if err != nil {
+			resourceName = definition["name"]
+		}
+		name := fmt.Sprintf("%s/%v", resourceType[strings.LastIndex(resourceType, "/")+1:], resourceName)
+		if strings.EqualFold(resourceType, "Microsoft.Resources/deployments") {
+			resources = append(resources, c.deployment(name, resourceType, definition, opts)...)
+			continue
+		}
+		r, err := c.evalFields(definition, armPriceFields)
+		if err != nil {
+			resources = append(resources, ArmResource{Name: name, Type: resourceType, Err: err})
+			continue
+		}
+		if condition, ok := r["condition"]; ok && condition == false {
+			continue
+		}
+		lines, err := armLines(name, resourceType, r, c.location, opts)
+		resources = append(resources, ArmResource{Name: name, Type: resourceType, Resources: lines, Err: err})
+	}
+	return resources
+}
+
+// deployment expands the resources of a nested deployment, which is how
+// Bicep compiles modules. Its template must be inline. With the inner
+// evaluation scope the template has its own parameters, given values by the
+// deployment, otherwise its expressions read those of the parent. Resource
+// names are prefixed with the deployment's.
+func (c *armContext) deployment(name, resourceType string, definition map[string]any, opts ArmOptions) []ArmResource {
+	fail := func(err error) []ArmResource {
+		return []ArmResource{{Name: name, Type: resourceType, Err: err}}
+	}
+	r, err := c.evalFields(definition, [][]string{{"condition"}, {"properties", "expressionEvaluationOptions"}})
+	if err != nil {
+		return fail(err)
+	}
+	if condition, ok := r["condition"]; ok && condition == false {
+		return nil
+	}
+	properties, _ := definition["properties"].(map[string]any)
+	inline, ok := properties["template"].(map[string]any)
+	if !ok {
+		return fail(&UnsupportedError{Type: resourceType, Reason: "only inline templates are supported, not templateLink"})
+	}
+	data, err := json.Marshal(inline)
+	if err != nil {
+		return fail(err)
+	}
+	var template Template
+	if err := json.Unmarshal(data, &template); err != nil {
+		return fail(fmt.Errorf("invalid nested template: %w", err))
+	}
+
+	var resources []ArmResource
+	if strings.EqualFold(stringValue(path(r, "properties", "expressionEvaluationOptions"), "scope"), "inner") {
+		parameters := map[string]any{}
+		values, _ := properties["parameters"].(map[string]any)
+		for key, v := range values {
+			p, _ := v.(map[string]any)
+			value, ok := p["value"]
+			if !ok {
+				// Key Vault references cannot be resolved offline.
+				continue
+			}
+			// A value that cannot be evaluated only fails the resources using it.
+			if parameters[key], err = c.eval(value); err != nil {
+				parameters[key] = armMissing(fmt.Sprintf("parameter %s: %v", key, err))
+			}
+		}
+		if resources, err = ArmResources(&template, parameters, opts); err != nil {
+			return fail(err)
+		}
+	} else {
+		definitions, err := templateResources(template.Resources)
+		if err != nil {
+			return fail(err)
+		}
+		copyIndex := c.copyIndex
+		for _, d := range definitions {
+			resources = append(resources, c.expand(d, opts)...)
+		}
+		c.copyIndex = copyIndex
+	}
+	for i := range resources {
+		resources[i].Name = name + "/" + resources[i].Name
+		for j := range resources[i].Resources {
+			resources[i].Resources[j].Name = name + "/" + resources[i].Resources[j].Name
+		}
+	}
+	return resources
+}
+
+// armLines maps an evaluated resource to estimate lines.
+func armLines(name, resourceType string, r map[string]any, defaultLocation string, opts ArmOptions) ([]Resource, error) {
+	region := NormalizeRegion(stringValue(r, "location"))
+	if region == "" || region == "global" {
+		region = NormalizeRegion(defaultLocation)
+	}
+	sku, _ := r["sku"].(map[string]any)
+	properties, _ := r["properties"].(map[string]any)
+	switch strings.ToLower(resourceType) {
+	case "microsoft.compute/virtualmachines":
+		size := stringValue(path(properties, "hardwareProfile"), "vmSize")
+		osDisk := path(properties, "storageProfile", "osDisk")
+		image := path(properties, "storageProfile", "imageReference")
+		windows := strings.EqualFold(stringValue(osDisk, "osType"), "Windows") ||
+			strings.Contains(strings.ToLower(stringValue(image, "publisher")+stringValue(image, "offer")), "windows")
+		lines := []Resource{VirtualMachine(name, size, region, windows, 1)}
+		if diskType := stringValue(path(osDisk, "managedDisk"), "storageAccountType"); diskType != "" {
+			size := 30.0
+			if windows {
+				size = 127
+			}
+			disk, err := ManagedDisk(name+"/osDisk", diskType, numberValue(osDisk, "diskSizeGB", size), region)
+			if err != nil {
+				return nil, &UnsupportedError{Type: resourceType, Reason: err.Error()}
+			}
+			lines = append(lines, disk)
+		}
+		return lines, nil
+	case "microsoft.compute/virtualmachinescalesets":
+		profile := path(properties, "virtualMachineProfile", "storageProfile")
+		windows := strings.EqualFold(stringValue(path(profile, "osDisk"), "osType"), "Windows") ||
+			strings.Contains(strings.ToLower(stringValue(path(profile, "imageReference"), "offer")), "windows")
+		return []Resource{VirtualMachine(name, stringValue(sku, "name"), region, windows, numberValue(sku, "capacity", 1))}, nil
+	case "microsoft.compute/disks":
+		disk, err := ManagedDisk(name, stringValue(sku, "name"), numberValue(properties, "diskSizeGB", 0), region)
+		if err != nil {
+			return nil, &UnsupportedError{Type: resourceType, Reason: err.Error()}
+		}
+		return []Resource{disk}, nil
+	case "microsoft.storage/storageaccounts":
+		tier, replication, _ := strings.Cut(stringValue(sku, "name"), "_")
+		if strings.EqualFold(tier, "Premium") {
+			return nil, &UnsupportedError{Type: resourceType, Reason: "premium storage accounts are not supported"}
+		}
+		return []Resource{BlobStorage(name, stringValue(properties, "accessTier"), replication, opts.StorageGB, region)}, nil
+	case "microsoft.web/serverfarms":
+		kind, _ := r["kind"].(string)
+		linux := strings.Contains(strings.ToLower(kind), "linux") || properties["reserved"] == true
+		return []Resource{AppServicePlan(name, stringValue(sku, "name"), linux, region, numberValue(sku, "capacity", 1))}, nil
+	case "microsoft.network/publicipaddresses":
+		return []Resource{PublicIP(name, stringValue(sku, "name"), stringValue(properties, "publicIPAllocationMethod"), region)}, nil
+	case "microsoft.containerservice/managedclusters":
+		var lines []Resource
+		pools, _ := properties["agentPoolProfiles"].([]any)
+		for _, p := range pools {
+			pool, _ := p.(map[string]any)
+			windows := strings.EqualFold(stringValue(pool, "osType"), "Windows")
+			line := VirtualMachine(fmt.Sprintf("%s/%s", name, stringValue(pool, "name")), stringValue(pool, "vmSize"), region, windows, numberValue(pool, "count", numberValue(pool, "minCount", 1)))
+			if strings.EqualFold(stringValue(pool, "scaleSetPriority"), "Spot") {
+				line.Meter = "Spot"
+			}
+			lines = append(lines, line)
+		}
+		return lines, nil
+	}
+	return nil, &UnsupportedError{Type: resourceType}
+}
+
+// freeArmTypes are resource types that have no price of their own.
+var freeArmTypes = map[string]bool{
+	"microsoft.network/virtualnetworks":                true,
+	"microsoft.network/virtualnetworks/subnets":        true,
+	"microsoft.network/networkinterfaces":              true,
+	"microsoft.network/networksecuritygroups":          true,
+	"microsoft.managedidentity/userassignedidentities": true,
+	"microsoft.authorization/roleassignments":          true,
+	"microsoft.resources/tags":                         true,
+}
+
+func path(values map[string]any, keys ...string) map[string]any {
+	for _, key := range keys {
+		values, _ = values[key].(map[string]any)
+	}
+	return values
+}
+
+// armPriceFields are the fields of a resource that armLines reads. Only
+// they are evaluated, so that functions that do not affect prices, such as
+// reference() or listKeys() in unrelated properties, do not make the
+// resource unresolvable.
+var armPriceFields = [][]string{
+	{"condition"},
+	{"location"},
+	{"sku"},
+	{"kind"},
+	{"properties", "hardwareProfile"},
+	{"properties", "storageProfile"},
+	{"properties", "virtualMachineProfile", "storageProfile"},
+	{"properties", "diskSizeGB"},
+	{"properties", "accessTier"},
+	{"properties", "reserved"},
+	{"properties", "publicIPAllocationMethod"},
+	{"properties", "agentPoolProfiles"},
+}
+
+// evalFields evaluates the fields of definition found at paths into a new
+// object with the same layout. Missing fields are left out.
+func (c *armContext) evalFields(definition map[string]any, paths [][]string) (map[string]any, error) {
+	out := map[string]any{}
+	for _, keys := range paths {
+		var v any = definition
+		evaluated, found := false, true
+		for _, key := range keys {
+			// An object may itself be an expression, such as variables('profile').
+			if s, ok := v.(string); ok && !evaluated {
+				var err error
+				if v, err = c.eval(s); err != nil {
+					return nil, fmt.Errorf("%s: %w", strings.Join(keys, "."), err)
+				}
+				evaluated = true
+			}
+			m, ok := v.(map[string]any)
+			if !ok {
+				found = false
+				break
+			}
+			if v, ok = m[key]; !ok {
+				found = false
+				break
+			}
+		}
+		if !found {
+			continue
+		}
+		if !evaluated {
+			var err error
+			if v, err = c.eval(v); err != nil {
+				return nil, fmt.Errorf("%s: %w", strings.Join(keys, "."), err)
+			}
+		}
+		parent := out
+		for _, key := range keys[:len(keys)-1] {
+			child, ok := parent[key].(map[string]any)
+			if !ok {
+				child = map[string]any{}
+				parent[key] = child
+			}
+			parent = child
+		}
+		parent[keys[len(keys)-1]] = v
+	}
+	return out, nil
+}
+
+// armMissing is a property that cannot be known without a deployment
+// value. Reading it fails with the reason.
+type armMissing string
+
+// armContext evaluates template expressions.
+type armContext struct {
+	template   *Template
+	parameters map[string]any
+	variables  map[string]any
+	resolving  map[string]bool
+	location   string
+	copyIndex  int
+}
+
+// eval evaluates the expressions found in v, recursively.
+func (c *armContext) eval(v any) (any, error) {
+	switch v := v.(type) {
+	case string:
+		if strings.HasPrefix(v, "[[") {
+			return v[1:], nil
+		}
+		if strings.HasPrefix(v, "[") && strings.HasSuffix(v, "]") {
+			p := &exprParser{ctx: c, s: v[1 : len(v)-1]}
+			result, err := p.parse()
+			if err != nil {
+				return nil, fmt.Errorf("expression %s: %w", v, err)
+			}
+			return result, nil
+		}
+		return v, nil
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for key, value := range v {
+			// Nested resources and copy loops are evaluated on their own, and
+			// dependencies and tags do not affect prices.
+			if key == "resources" || key == "copy" || key == "dependsOn" || key == "tags" {
+				continue
+			}
+			evaluated, err := c.eval(value)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = evaluated
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(v))
+		for i, value := range v {
+			evaluated, err := c.eval(value)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = evaluated
+		}
+		return out, nil
+	}
+	return v, nil
+}
+
+func (c *armContext) parameter(name string) (any, error) {
+	if v, ok := c.parameters[strings.ToLower(name)]; ok {
+		if missing, ok := v.(armMissing); ok {
+			return nil, errors.New(string(missing))
+		}
+		return v, nil
+	}
+	for declared, p := range c.template.Parameters {
+		if strings.EqualFold(declared, name) {
+			if p.DefaultValue == nil {
+				return nil, fmt.Errorf("parameter %q has no value", name)
+			}
+			v, err := c.eval(p.DefaultValue)
+			if err != nil {
+				return nil, err
+			}
+			c.parameters[strings.ToLower(name)] = v
+			return v, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown parameter %q", name)
+}
+
+func (c *armContext) variable(name string) (any, error) {
+	key := strings.ToLower(name)
+	if v, ok := c.variables[key]; ok {
+		return v, nil
+	}
+	for declared, raw := range c.template.Variables {
+		if strings.EqualFold(declared, name) {
+			if c.resolving[key] {
+				return nil, fmt.Errorf("variable %q references itself", name)
+			}
+			c.resolving[key] = true
+			v, err := c.eval(raw)
+			delete(c.resolving, key)
+			if err != nil {
+				return nil, err
+			}
+			c.variables[key] = v
+			return v, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown variable %q", name)
+}
+
+// exprParser parses and evaluates a template expression such as
+// concat(parameters('prefix'), '-', copyIndex()).
+type exprParser struct {
+	ctx *armContext
+	s   string
+	pos int
+}
+
+func (p *exprParser) parse() (any, error) {
+	v, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	p.space()
+	if p.pos != len(p.s) {
+		return nil, fmt.Errorf("unexpected %q", p.s[p.pos:])
+	}
+	return v, nil
+}
+
+func (p *exprParser) space() {
+	for p.pos < len(p.s) && p.s[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+func (p *exprParser) peek() byte {
+	p.space()
+	if p.pos < len(p.s) {
+		return p.s[p.pos]
+	}
+	return 0
+}
+
+func (p *exprParser) expect(b byte) error {
+	if p.peek() != b {
+		return fmt.Errorf("expected %q at %d", b, p.pos)
+	}
+	p.pos++
+	return nil
+}
+
+// expression parses a literal or a function call followed by property and
+// index accessors.
+func (p *exprParser) expression() (any, error) {
+	v, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch p.peek() {
+		case '.':
+			p.pos++
+			name := p.identifier()
+			m, ok := v.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("cannot read property %q of %v", name, v)
+			}
+			v = lookup(m, name)
+			if missing, ok := v.(armMissing); ok {
+				return nil, errors.New(string(missing))
+			}
+		case '[':
+			p.pos++
+			index, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(']'); err != nil {
+				return nil, err
+			}
+			switch container := v.(type) {
+			case []any:
+				i, err := toInt(index)
+				if err != nil || i < 0 || i >= len(container) {
+					return nil, fmt.Errorf("index %v out of range", index)
+				}
+				v = container[i]
+			case map[string]any:
+				v = lookup(container, fmt.Sprint(index))
+				if missing, ok := v.(armMissing); ok {
+					return nil, errors.New(string(missing))
+				}
+			default:
+				return nil, fmt.Errorf("cannot index %v", v)
+			}
+		default:
+			return v, nil
+		}
+	}
+}
+
+func lookup(m map[string]any, name string) any {
+	if v, ok := m[name]; ok {
+		return v
+	}
+	for key, v := range m {
+		if strings.EqualFold(key, name) {
+			return v
+		}
+	}
+	return nil
+}
+
+func (p *exprParser) primary() (any, error) {
+	switch c := p.peek(); {
+	case c == '\'':
+		return p.literal()
+	case c == '-' || (c >= '0' && c <= '9'):
+		start := p.pos
+		p.pos++
+		for p.pos < len(p.s) && (p.s[p.pos] >= '0' && p.s[p.pos] <= '9' || p.s[p.pos] == '.') {
+			p.pos++
+		}
+		return strconv.ParseFloat(p.s[start:p.pos], 64)
+	}
+	name := p.identifier()
+	if name == "" {
+		return nil, fmt.Errorf("unexpected %q", p.s[p.pos:])
+	}
+	if err := p.expect('('); err != nil {
+		return nil, err
+	}
+	var args []any
+	for p.peek() != ')' {
+		arg, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if p.peek() == ',' {
+			p.pos++
+		}
+	}
+	p.pos++
+	return p.ctx.call(name, args)
+}
+
+func (p *exprParser) literal() (string, error) {
+	p.pos++
+	var b strings.Builder
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		p.pos++
+		if c == '\'' {
+			if p.pos < len(p.s) && p.s[p.pos] == '\'' {
+				b.WriteByte('\'')
+				p.pos++
+				continue
+			}
+			return b.String(), nil
+		}
+		b.WriteByte(c)
+	}
+	return "", fmt.Errorf("unterminated string")
+}
+
+func (p *exprParser) identifier() string {
+	p.space()
+	start := p.pos
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+			break
+		}
+		p.pos++
+	}
+	return p.s[start:p.pos]
+}
+
+// call evaluates the template functions that commonly decide SKUs, regions
+// and counts. Other functions make the resource unresolvable when they are
+// used by one of its armPriceFields.
+func (c *armContext) call(name string, args []any) (any, error) {
+	arg := func(i int) any {
+		if i < len(args) {
+			return args[i]
+		}
+		return nil
+	}
+	number := func(i int) (float64, error) {
+		n, err := toInt(arg(i))
+		return float64(n), err
+	}
+	switch strings.ToLower(name) {
+	case "parameters":
+		return c.parameter(fmt.Sprint(arg(0)))
+	case "variables":
+		return c.variable(fmt.Sprint(arg(0)))
+	case "resourcegroup":
+		var location any = c.location
+		if c.location == "" {
+			location = armMissing("resourceGroup().location is unknown, set the target location with --location")
+		}
+		return map[string]any{"location": location, "name": "resourceGroup", "id": "/resourceGroups/resourceGroup"}, nil
+	case "subscription":
+		return map[string]any{"subscriptionId": "00000000-0000-0000-0000-000000000000", "id": "/subscriptions/00000000-0000-0000-0000-000000000000"}, nil
+	case "deployment":
+		return map[string]any{"name": "deployment"}, nil
+	case "copyindex":
+		offset := 0.0
+		for _, a := range args {
+			if n, err := toInt(a); err == nil {
+				offset = float64(n)
+			}
+		}
+		return float64(c.copyIndex) + offset, nil
+	case "concat":
+		if _, ok := arg(0).([]any); ok {
+			var out []any
+			for _, a := range args {
+				list, _ := a.([]any)
+				out = append(out, list...)
+			}
+			return out, nil
+		}
+		var b strings.Builder
+		for _, a := range args {
+			b.WriteString(toString(a))
+		}
+		return b.String(), nil
+	case "format":
+		format := toString(arg(0))
+		for i, a := range args[1:] {
+			format = strings.ReplaceAll(format, "{"+strconv.Itoa(i)+"}", toString(a))
+		}
+		return format, nil
+	case "tolower":
+		return strings.ToLower(toString(arg(0))), nil
+	case "toupper":
+		return strings.ToUpper(toString(arg(0))), nil
+	case "replace":
+		return strings.ReplaceAll(toString(arg(0)), toString(arg(1)), toString(arg(2))), nil
+	case "string":
+		return toString(arg(0)), nil
+	case "int":
+		n, err := number(0)
+		return n, err
+	case "bool":
+		return arg(0) == true || strings.EqualFold(toString(arg(0)), "true"), nil
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "equals":
+		return fmt.Sprint(arg(0)) == fmt.Sprint(arg(1)), nil
+	case "not":
+		return arg(0) != true, nil
+	case "and", "or":
+		result := strings.EqualFold(name, "and")
+		for _, a := range args {
+			if strings.EqualFold(name, "and") {
+				result = result && a == true
+			} else {
+				result = result || a == true
+			}
+		}
+		return result, nil
+	case "if":
+		if arg(0) == true {
+			return arg(1), nil
+		}
+		return arg(2), nil
+	case "coalesce":
+		for _, a := range args {
+			if a != nil {
+				return a, nil
+			}
+		}
+		return nil, nil
+	case "empty":
+		switch v := arg(0).(type) {
+		case nil:
+			return true, nil
+		case string:
+			return v == "", nil
+		case []any:
+			return len(v) == 0, nil
+		case map[string]any:
+			return len(v) == 0, nil
+		}
+		return false, nil
+	case "length":
+		switch v := arg(0).(type) {
+		case string:
+			return float64(len(v)), nil
+		case []any:
+			return float64(len(v)), nil
+		case map[string]any:
+			return float64(len(v)), nil
+		}
+		return nil, fmt.Errorf("length of %v", arg(0))
+	case "add", "sub", "mul", "div", "mod":
+		a, err := number(0)
+		if err != nil {
+			return nil, err
+		}
+		b, err := number(1)
+		if err != nil {
+			return nil, err
+		}
+		switch strings.ToLower(name) {
+		case "add":
+			return a + b, nil
+		case "sub":
+			return a - b, nil
+		case "mul":
+			return a * b, nil
+		case "div", "mod":
+			if b == 0 {
+				return nil, fmt.Errorf("division by zero")
+			}
+			if strings.EqualFold(name, "mod") {
+				return float64(int(a) % int(b)), nil
+			}
+			return float64(int(a) / int(b)), nil
+		}
+	case "min", "max":
+		var result float64
+		for i := range args {
+			n, err := number(i)
+			if err != nil {
+				return nil, err
+			}
+			if i == 0 || (strings.EqualFold(name, "min") && n < result) || (strings.EqualFold(name, "max") && n > result) {
+				result = n
+			}
+		}
+		return result, nil
+	case "uniquestring":
+		h := fnv.New64a()
+		for _, a := range args {
+			h.Write([]byte(toString(a)))
+		}
+		return strconv.FormatUint(h.Sum64(), 36), nil
+	case "resourceid":
+		parts := make([]string, len(args))
+		for i, a := range args {
+			parts[i] = toString(a)
+		}
+		return strings.Join(parts, "/"), nil
+	}
+	return nil, fmt.Errorf("function %s() is not supported", name)
+}
+
+func toString(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case string:
+		return v
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+func toInt(v any) (int, error) {
+	switch v := v.(type) {
+	case float64:
+		return int(v), nil
+	case string:
+		return strconv.Atoi(v)
+	}
+	return 0, fmt.Errorf("%v is not a number", v)
+}
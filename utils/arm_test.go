@@ -0,0 +1,174 @@
+package utils
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestArmExpressions(t *testing.T) {
+	template := &Template{Variables: map[string]any{
+		"prefix": "[toLower(parameters('Env'))]",
+		"sizes":  map[string]any{"prod": "Standard_D4s_v5", "dev": "Standard_B2s"},
+	}}
+	if err := json.Unmarshal([]byte(`{"env": {"type": "string", "defaultValue": "Dev"}, "count": {"type": "int"}}`), &template.Parameters); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		expression string
+		location   string
+		want       any
+		err        string
+	}{
+		{"parameter default ignoring case", "[parameters('ENV')]", "", "Dev", ""},
+		{"parameter without value", "[parameters('count')]", "", nil, `parameter "count" has no value`},
+		{"variable from a parameter", "[variables('prefix')]", "", "dev", ""},
+		{"object variable", "[variables('sizes')[variables('prefix')]]", "", "Standard_B2s", ""},
+		{"concat", "[concat(variables('prefix'), '-vm-', copyIndex(1))]", "", "dev-vm-3", ""},
+		{"format", "[format('{0}-{1}', variables('prefix'), 'db')]", "", "dev-db", ""},
+		{"if", "[if(equals(parameters('env'), 'Prod'), 'P1v3', 'B1')]", "", "B1", ""},
+		{"escaped bracket", "[[not an expression]", "", "[not an expression]", ""},
+		{"resource group location", "[resourceGroup().location]", "westeurope", "westeurope", ""},
+		{"resource group location without --location", "[resourceGroup().location]", "", nil, "set the target location with --location"},
+		{"unknown function", "[reference('vm').id]", "", nil, "reference"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &armContext{template: template, parameters: map[string]any{}, variables: map[string]any{}, resolving: map[string]bool{}, location: tt.location, copyIndex: 2}
+			got, err := c.eval(tt.expression)
+			switch {
+			case tt.err != "":
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("got %v, %v, want an error containing %q", got, err, tt.err)
+				}
+			case err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case !reflect.DeepEqual(got, tt.want):
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestArmResources(t *testing.T) {
+	const vms = `{
+		"parameters": {
+			"vmCount": {"type": "int", "defaultValue": 1},
+			"vmSize": {"type": "string", "defaultValue": "Standard_D2s_v5"},
+			"deployDisk": {"type": "bool", "defaultValue": false}
+		},
+		"resources": [
+			{
+				"type": "Microsoft.Compute/virtualMachines",
+				"name": "[concat('vm', copyIndex())]",
+				"location": "[resourceGroup().location]",
+				"copy": {"name": "vms", "count": "[parameters('vmCount')]"},
+				"properties": {"hardwareProfile": {"vmSize": "[parameters('vmSize')]"}}
+			},
+			{
+				"type": "Microsoft.Compute/disks",
+				"name": "data",
+				"condition": "[parameters('deployDisk')]",
+				"location": "northeurope",
+				"sku": {"name": "Premium_LRS"},
+				"properties": {"diskSizeGB": 128}
+			},
+			{"type": "Microsoft.Network/virtualNetworks", "name": "vnet"}
+		]
+	}`
+	const module = `{
+		"parameters": {"size": {"type": "string", "defaultValue": "Standard_B2s"}},
+		"resources": [{
+			"type": "Microsoft.Resources/deployments",
+			"name": "app",
+			"properties": {
+				"expressionEvaluationOptions": {"scope": "inner"},
+				"parameters": {"vmSize": {"value": "[parameters('size')]"}},
+				"template": {
+					"parameters": {"vmSize": {"type": "string"}},
+					"resources": [{
+						"type": "Microsoft.Compute/virtualMachines",
+						"name": "vm",
+						"location": "[resourceGroup().location]",
+						"properties": {"hardwareProfile": {"vmSize": "[parameters('vmSize')]"}}
+					}]
+				}
+			}
+		}, {
+			"type": "Microsoft.Resources/deployments",
+			"name": "linked",
+			"properties": {"templateLink": {"uri": "https://example.com/t.json"}}
+		}]
+	}`
+	type priced struct {
+		name, sku, region string
+		quantity          float64
+	}
+	tests := []struct {
+		name       string
+		template   string
+		parameters map[string]any
+		location   string
+		want       []priced
+		errs       []string
+	}{
+		{"defaults", vms, nil, "westeurope", []priced{{"virtualMachines/vm0", "Standard_D2s_v5", "westeurope", 1}}, nil},
+		{"copy loop with a count parameter", vms, map[string]any{"vmCount": 3.0}, "westeurope", []priced{
+			{"virtualMachines/vm0", "Standard_D2s_v5", "westeurope", 1},
+			{"virtualMachines/vm1", "Standard_D2s_v5", "westeurope", 1},
+			{"virtualMachines/vm2", "Standard_D2s_v5", "westeurope", 1},
+		}, nil},
+		{"parameter names ignore case", vms, map[string]any{"VMSIZE": "Standard_E2s_v5", "DeployDisk": true}, "westeurope", []priced{
+			{"virtualMachines/vm0", "Standard_E2s_v5", "westeurope", 1},
+			{"disks/data", "P10 LRS", "northeurope", 1},
+		}, nil},
+		{"resource group location without --location", vms, nil, "", nil, []string{"set the target location with --location"}},
+		{"nested deployment", module, map[string]any{"size": "Standard_D4s_v5"}, "westeurope", []priced{
+			{"deployments/app/virtualMachines/vm", "Standard_D4s_v5", "westeurope", 1},
+		}, []string{"only inline templates are supported"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var template Template
+			if err := json.Unmarshal([]byte(tt.template), &template); err != nil {
+				t.Fatal(err)
+			}
+			resources, err := ArmResources(&template, tt.parameters, ArmOptions{Location: tt.location})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []priced
+			var errs []string
+			for _, r := range resources {
+				if r.Err != nil {
+					errs = append(errs, r.Err.Error())
+					continue
+				}
+				for _, line := range r.Resources {
+					got = append(got, priced{line.Name, line.SKU, line.Region, line.quantity()})
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("resources %+v, want %+v", got, tt.want)
+			}
+			if len(errs) != len(tt.errs) {
+				t.Fatalf("errors %q, want %q", errs, tt.errs)
+			}
+			for i, want := range tt.errs {
+				if !strings.Contains(errs[i], want) {
+					t.Errorf("error %q, want it to contain %q", errs[i], want)
+				}
+			}
+		})
+	}
+
+	var template Template
+	if err := json.Unmarshal([]byte(vms), &template); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ArmResources(&template, map[string]any{"nope": 1.0}, ArmOptions{}); err == nil {
+		t.Error("an undeclared parameter was accepted")
+	}
+}
@@ -0,0 +1,215 @@
+package utils
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultCacheTTL is how long cached prices are served before being fetched again.
+const DefaultCacheTTL = 24 * time.Hour
+
+// ErrNotCached is returned in offline mode for a query that is not cached.
+var ErrNotCached = errors.New("prices are not cached, run the command once without --offline")
+
+// Cache stores the items of complete queries on disk, one gzipped JSON lines
+// file per currency, API version and filter. The first line of a file is its
+// CacheEntry, the others are items.
+type Cache struct {
+	Dir string
+	TTL time.Duration
+	// Offline serves entries whatever their age and never queries the API.
+	Offline bool
+	// Refresh ignores cached entries, fetching and storing them again.
+	Refresh bool
+}
+
+// CacheEntry describes a cached query.
+type CacheEntry struct {
+	Currency   string    `json:"currency"`
+	APIVersion string    `json:"apiVersion"`
+	Filter     string    `json:"filter"`
+	FetchedAt  time.Time `json:"fetchedAt"`
+	Items      int       `json:"items"`
+	// Path and Size are set when listing the cache.
+	Path string `json:"-"`
+	Size int64  `json:"-"`
+}
+
+// DefaultCacheDir returns the cloudcost directory of the user cache dir.
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "cloudcost"), nil
+}
+
+func (c *Cache) path(currency, apiVersion, filter string) string {
+	sum := sha256.Sum256([]byte(currency + "\n" + apiVersion + "\n" + filter))
+	return filepath.Join(c.Dir, hex.EncodeToString(sum[:])+".jsonl.gz")
+}
+
+// load returns the cached items of a query, or ok false when there is no
+// usable entry.
+func (c *Cache) load(currency, apiVersion, filter string) (items []Item, ok bool, err error) {
+	if c.Refresh {
+		return nil, false, nil
+	}
+	f, err := os.Open(c.path(currency, apiVersion, filter))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+	defer f.Close()
+	entry, items, err := readCacheFile(f, true)
+	if err != nil {
+		// A corrupt entry is fetched again rather than failing the command.
+		return nil, false, nil
+	}
+	if !c.Offline && time.Since(entry.FetchedAt) > c.TTL {
+		return nil, false, nil
+	}
+	return items, true, nil
+}
+
+// store writes the items of a complete query, replacing any previous entry.
+func (c *Cache) store(currency, apiVersion, filter string, items []Item) error {
+	if err := os.MkdirAll(c.Dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(c.Dir, "entry-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	zw := gzip.NewWriter(tmp)
+	enc := json.NewEncoder(zw)
+	entry := CacheEntry{Currency: currency, APIVersion: apiVersion, Filter: filter, FetchedAt: time.Now().UTC(), Items: len(items)}
+	if err := enc.Encode(entry); err != nil {
+		tmp.Close()
+		return err
+	}
+	for _, item := range items {
+		if err := enc.Encode(item); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	// Renaming makes the entry visible only once it is complete.
+	return os.Rename(tmp.Name(), c.path(currency, apiVersion, filter))
+}
+
+func readCacheFile(f *os.File, withItems bool) (CacheEntry, []Item, error) {
+	var entry CacheEntry
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return entry, nil, err
+	}
+	defer zr.Close()
+	dec := json.NewDecoder(bufio.NewReader(zr))
+	if err := dec.Decode(&entry); err != nil {
+		return entry, nil, err
+	}
+	if !withItems {
+		return entry, nil, nil
+	}
+	items := make([]Item, 0, entry.Items)
+	for dec.More() {
+		var item Item
+		if err := dec.Decode(&item); err != nil {
+			return entry, nil, err
+		}
+		items = append(items, item)
+	}
+	if len(items) != entry.Items {
+		return entry, nil, fmt.Errorf("truncated cache entry")
+	}
+	return entry, items, nil
+}
+
+// Entries lists the cached queries, most recently fetched first.
+func (c *Cache) Entries() ([]CacheEntry, error) {
+	paths, err := filepath.Glob(filepath.Join(c.Dir, "*.jsonl.gz"))
+	if err != nil {
+		return nil, err
+	}
+	var entries []CacheEntry
+	for _, path := range paths {
+		entry, err := readEntry(path)
+		if err != nil {
+			// Unreadable entries are listed so that they can be cleared.
+			entry = CacheEntry{Filter: "(unreadable: " + err.Error() + ")"}
+		}
+		entry.Path = path
+		if info, err := os.Stat(path); err == nil {
+			entry.Size = info.Size()
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].FetchedAt.After(entries[j].FetchedAt)
+	})
+	return entries, nil
+}
+
+func readEntry(path string) (CacheEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return CacheEntry{}, err
+	}
+	defer f.Close()
+	entry, _, err := readCacheFile(f, false)
+	return entry, err
+}
+
+// Prune removes the entries fetched more than olderThan ago, and unreadable
+// ones. It returns the removed entries.
+func (c *Cache) Prune(olderThan time.Duration) ([]CacheEntry, error) {
+	entries, err := c.Entries()
+	if err != nil {
+		return nil, err
+	}
+	var removed []CacheEntry
+	for _, entry := range entries {
+		if time.Since(entry.FetchedAt) <= olderThan {
+			continue
+		}
+		if err := os.Remove(entry.Path); err != nil {
+			return removed, err
+		}
+		removed = append(removed, entry)
+	}
+	return removed, nil
+}
+
+// Clear removes every entry of the cache.
+func (c *Cache) Clear() ([]CacheEntry, error) {
+	return c.Prune(-1)
+}
+
+// Key returns a short identifier of the entry, the start of its file name.
+func (e CacheEntry) Key() string {
+	key := strings.TrimSuffix(filepath.Base(e.Path), ".jsonl.gz")
+	if len(key) > 12 {
+		key = key[:12]
+	}
+	return key
+}
@@ -1,12 +1,173 @@
 package utils
 
-func calculateUsageHourly(period float64, days int, usagePerHour float64) float64 {
-	hours := days * 24 // convert days to hours
-	totalUsage := period * float64(hours) * usagePerHour
-	return totalUsage
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// HoursPerMonth is the number of hours Azure bills for a month.
+const HoursPerMonth = 730
+
+// Consumption is the usage to price an item against.
+type Consumption struct {
+	// Hours the resource runs, or holds Data for DataTime units.
+	Hours float64
+	// GB transferred for Data units, or held for DataTime units.
+	GB float64
+	// Count of operations, events or transactions.
+	Count float64
+}
+
+// Quantity returns the usage of consumption in the dimension of unit: hours,
+// GB or count.
+func (c Consumption) Quantity(unit Unit) float64 {
+	switch unit.Dimension {
+	case Time:
+		return c.Hours
+	case Data, DataTime:
+		return c.GB
+	}
+	return c.Count
+}
+
+// WithQuantity returns consumption with its usage in the dimension of unit
+// replaced by quantity.
+func (c Consumption) WithQuantity(unit Unit, quantity float64) Consumption {
+	switch unit.Dimension {
+	case Time:
+		c.Hours = quantity
+	case Data, DataTime:
+		c.GB = quantity
+	default:
+		c.Count = quantity
+	}
+	return c
+}
+
+// Cost returns what consumption costs for an item priced at price per unit.
+func Cost(unit Unit, price float64, consumption Consumption) float64 {
+	switch unit.Dimension {
+	case Time:
+		return calculateUsageHourly(unit, price, consumption.Hours) * unit.packs(consumption.Count)
+	case Data:
+		return calculateUsageGB(unit, price, consumption.GB)
+	case DataTime:
+		return calculateUsageStored(unit, price, consumption.GB, consumption.Hours)
+	}
+	return calculateUsageEvents(unit, price, consumption.Count)
+}
+
+// MonthlyPrice returns the cost of running a time metered item for a month.
+// count is the number of items of units priced per items and period, such as
+// "10 Million/Month", 0 pricing the items the unit covers. It reports false
+// for units that are not billed by time.
+func MonthlyPrice(unit Unit, price float64, count float64) (float64, bool) {
+	if unit.Dimension != Time {
+		return 0, false
+	}
+	return calculateUsageHourly(unit, price, HoursPerMonth) * unit.packs(count), true
+}
+
+// packs returns how many times the price of a unit priced per items and
+// period is billed for count items. Without a count, or for other units, it
+// is billed once.
+func (u Unit) packs(count float64) float64 {
+	if u.Count == 0 || count == 0 {
+		return 1
+	}
+	return count / u.Count
+}
+
+func calculateUsageHourly(unit Unit, price float64, hours float64) float64 {
+	return price * hours / unit.Quantity
 }
 
-func calculateUsageGB(bandwidth float64, days int, usagePerGB float64) float64 {
-	totalUsage := bandwidth * float64(days) * usagePerGB
-	return totalUsage
+func calculateUsageGB(unit Unit, price float64, gb float64) float64 {
+	return price * gb / unit.Quantity
+}
+
+func calculateUsageStored(unit Unit, price float64, gb float64, hours float64) float64 {
+	return price * gb / unit.Quantity * hours / unit.Period
+}
+
+func calculateUsageEvents(unit Unit, price float64, count float64) float64 {
+	return price * count / unit.Quantity
+}
+
+// TermMonths returns the length in months of a reservation or savings plan
+// term such as "1 Year" or "3 Years", or 0 when it cannot be read.
+func TermMonths(term string) int {
+	fields := strings.Fields(term)
+	if len(fields) != 2 {
+		return 0
+	}
+	n, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return 0
+	}
+	switch strings.TrimSuffix(strings.ToLower(fields[1]), "s") {
+	case "year":
+		return n * 12
+	case "month":
+		return n
+	}
+	return 0
+}
+
+// MeterKey identifies the price of a meter whatever its tier: the meter and
+// SKU, the price type and reservation term and the region.
+func MeterKey(item Item) string {
+	return strings.Join([]string{item.MeterID, item.SkuID, item.Type, item.ReservationTerm, item.ArmRegionName}, "|")
+}
+
+// Tier is the part of a usage billed at one tier of a graduated price.
+type Tier struct {
+	Item Item
+	// Quantity is the usage in the tier, in hours, GB or count.
+	Quantity float64
+	Cost     float64
+}
+
+// TieredCost spreads consumption over the tiers of a meter, its items with
+// different tierMinimumUnits, read in hours, GB or count. Each tier bills the
+// usage above its minimum up to the next tier's, and usage below the lowest
+// minimum is free. Every tier is returned, lowest first, even those the usage
+// does not reach.
+func TieredCost(items []Item, consumption Consumption) ([]Tier, error) {
+	if len(items) == 0 {
+		return nil, nil
+	}
+	sorted := append([]Item{}, items...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].TierMinimumUnits < sorted[j].TierMinimumUnits
+	})
+	unit, err := ParseUnit(sorted[0].UnitOfMeasure)
+	if err != nil {
+		return nil, err
+	}
+	if sorted[0].TierMinimumUnits > 0 {
+		free := sorted[0]
+		free.RetailPrice, free.UnitPrice, free.TierMinimumUnits = 0, 0, 0
+		sorted = append([]Item{free}, sorted...)
+	}
+
+	quantity := consumption.Quantity(unit)
+	var tiers []Tier
+	for i, item := range sorted {
+		if i > 0 && item.TierMinimumUnits == sorted[i-1].TierMinimumUnits {
+			continue
+		}
+		upper := math.Inf(1)
+		for _, next := range sorted[i+1:] {
+			if next.TierMinimumUnits > item.TierMinimumUnits {
+				upper = next.TierMinimumUnits
+				break
+			}
+		}
+		q := math.Max(0, math.Min(quantity, upper)-item.TierMinimumUnits)
+		tiers = append(tiers, Tier{Item: item, Quantity: q, Cost: Cost(unit, item.RetailPrice, consumption.WithQuantity(unit, q))})
+	}
+	return tiers, nil
 }
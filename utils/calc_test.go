@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTieredCost(t *testing.T) {
+	tier := func(minimum, price float64) Item {
+		return Item{MeterID: "m", UnitOfMeasure: "1 GB", TierMinimumUnits: minimum, RetailPrice: price}
+	}
+	type billed struct{ minimum, quantity, cost float64 }
+	graduated := []Item{tier(0, 0.1), tier(10, 0.08), tier(50, 0.05)}
+	tests := []struct {
+		name  string
+		items []Item
+		gb    float64
+		want  []billed
+	}{
+		{"within the first tier", graduated, 4, []billed{{0, 4, 0.4}, {10, 0, 0}, {50, 0, 0}}},
+		{"across two boundaries", graduated, 100, []billed{{0, 10, 1}, {10, 40, 3.2}, {50, 50, 2.5}}},
+		{"unsorted tiers", []Item{tier(50, 0.05), tier(0, 0.1), tier(10, 0.08)}, 100, []billed{{0, 10, 1}, {10, 40, 3.2}, {50, 50, 2.5}}},
+		{"free first tier", []Item{tier(5, 2.5)}, 50, []billed{{0, 5, 0}, {5, 45, 112.5}}},
+		{"below the first minimum", []Item{tier(5, 2.5)}, 3, []billed{{0, 3, 0}, {5, 0, 0}}},
+		{"duplicate tier rows", []Item{tier(0, 0.1), tier(0, 0.1), tier(10, 0.08)}, 20, []billed{{0, 10, 1}, {10, 10, 0.8}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tiers, err := TieredCost(tt.items, Consumption{GB: tt.gb})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(tiers) != len(tt.want) {
+				t.Fatalf("got %d tiers %+v, want %d", len(tiers), tiers, len(tt.want))
+			}
+			for i, want := range tt.want {
+				got := tiers[i]
+				if got.Item.TierMinimumUnits != want.minimum || math.Abs(got.Quantity-want.quantity) > 1e-9 || math.Abs(got.Cost-want.cost) > 1e-9 {
+					t.Errorf("tier %d: minimum %g quantity %g cost %g, want %+v", i, got.Item.TierMinimumUnits, got.Quantity, got.Cost, want)
+				}
+			}
+		})
+	}
+}
+
+func TestMeterKey(t *testing.T) {
+	base := Item{MeterID: "m", SkuID: "s", Type: "Consumption", ArmRegionName: "westeurope"}
+	tier := base
+	tier.TierMinimumUnits, tier.RetailPrice = 100, 0.5
+	region := base
+	region.ArmRegionName = "northeurope"
+	reserved := base
+	reserved.Type, reserved.ReservationTerm = "Reservation", "1 Year"
+	if MeterKey(base) != MeterKey(tier) {
+		t.Error("the tiers of a meter have different keys")
+	}
+	if MeterKey(base) == MeterKey(region) || MeterKey(base) == MeterKey(reserved) {
+		t.Error("a meter shares its key with another region or price type")
+	}
+}
@@ -0,0 +1,396 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultBaseURL is the public Azure Retail Prices endpoint.
+	DefaultBaseURL = "https://prices.azure.com/api/retail/prices"
+	// DefaultAPIVersion is the Retail Prices API version requested by the client.
+	DefaultAPIVersion = "2023-01-01-preview"
+)
+
+// Client is a typed client for the Azure Retail Prices API.
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+	Currency   string
+	APIVersion string
+	// MaxRetries is the number of times a page is retried after a 429 or a 5xx response.
+	MaxRetries int
+	// Backoff is the initial delay between retries, doubled after every attempt.
+	Backoff time.Duration
+	// Cache, when set, serves complete queries from disk.
+	Cache *Cache
+	// Concurrency is the number of pages fetched at the same time once the
+	// first page shows there are more.
+	Concurrency int
+	// Progress, when set, is called after each page of a query of several
+	// pages, and once more with done set when the query ends.
+	Progress func(pages, items int, done bool)
+
+	mu          sync.Mutex
+	pausedUntil time.Time
+}
+
+// NewClient returns a client for the public endpoint using the given currency.
+func NewClient(currency string) *Client {
+	return &Client{
+		BaseURL:     DefaultBaseURL,
+		HTTPClient:  &http.Client{Timeout: 30 * time.Second},
+		Currency:    currency,
+		APIVersion:  DefaultAPIVersion,
+		MaxRetries:  4,
+		Backoff:     time.Second,
+		Concurrency: 4,
+	}
+}
+
+// StatusError is returned when the API answers with a non 200 status.
+type StatusError struct {
+	StatusCode int
+	Status     string
+	Body       string
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("request failed with status %s: %s", e.Status, e.Body)
+}
+
+// Temporary reports whether the request may succeed if retried.
+func (e *StatusError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// PartialResultError is returned when pagination fails after some pages were
+// already fetched. The items fetched so far are still returned alongside it.
+type PartialResultError struct {
+	Pages int
+	Items int
+	Err   error
+}
+
+func (e *PartialResultError) Error() string {
+	return fmt.Sprintf("partial result after %d pages (%d items): %v", e.Pages, e.Items, e.Err)
+}
+
+func (e *PartialResultError) Unwrap() error {
+	return e.Err
+}
+
+// List fetches every item matching filter, following NextPageLink.
+func (c *Client) List(ctx context.Context, filter Filter) ([]Item, error) {
+	return ListItems(ctx, c, filter)
+}
+
+// Each calls fn for every item matching filter, page by page. A nil filter
+// walks the whole catalog.
+func (c *Client) Each(ctx context.Context, filter Filter, fn func(Item) error) error {
+	if c.Cache == nil {
+		return c.each(ctx, filter, fn)
+	}
+	filterString := ""
+	if filter != nil {
+		filterString = filter.String()
+	}
+	items, ok, err := c.Cache.load(c.Currency, c.APIVersion, filterString)
+	if err != nil {
+		return err
+	}
+	if ok {
+		for _, item := range items {
+			if err := fn(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if c.Cache.Offline {
+		return fmt.Errorf("%s: %w", filterString, ErrNotCached)
+	}
+
+	// Only complete results are cached.
+	items = nil
+	err = c.each(ctx, filter, func(item Item) error {
+		items = append(items, item)
+		return fn(item)
+	})
+	if err != nil {
+		return err
+	}
+	// A cache that cannot be written only costs a query the next time.
+	_ = c.Cache.store(c.Currency, c.APIVersion, filterString, items)
+	return nil
+}
+
+func (c *Client) each(ctx context.Context, filter Filter, fn func(Item) error) error {
+	first := c.firstPage(filter)
+	resp, err := c.page(ctx, first)
+	if err != nil {
+		return err
+	}
+	pages, count := 1, 0
+	defer func() {
+		if pages > 1 && c.Progress != nil {
+			c.Progress(pages, count, true)
+		}
+	}()
+	emit := func(resp *Response) error {
+		for _, item := range resp.Items {
+			if err := fn(item); err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	}
+	if err := emit(resp); err != nil {
+		return err
+	}
+	next := resp.NextPageLink
+	pageSize := skipOf(next)
+	if next != "" && c.Concurrency > 1 && pageSize > 0 {
+		return c.eachConcurrent(ctx, first, pageSize, emit, &pages, &count)
+	}
+	for next != "" {
+		resp, err := c.page(ctx, next)
+		if err != nil {
+			return &PartialResultError{Pages: pages, Items: count, Err: err}
+		}
+		pages++
+		if err := emit(resp); err != nil {
+			return err
+		}
+		if c.Progress != nil {
+			c.Progress(pages, count, false)
+		}
+		next = resp.NextPageLink
+	}
+	return nil
+}
+
+// skipOf returns the $skip of a NextPageLink, which is the page size after
+// the first page, or 0 when there is none.
+func skipOf(link string) int {
+	u, err := url.Parse(link)
+	if err != nil {
+		return 0
+	}
+	skip, _ := strconv.Atoi(u.Query().Get("$skip"))
+	return skip
+}
+
+// eachConcurrent fetches the pages after the first one with up to
+// c.Concurrency requests in flight, addressing them with $skip. Pages are
+// emitted in order; the end of the result is the first page without a
+// NextPageLink.
+func (c *Client) eachConcurrent(ctx context.Context, first string, pageSize int, emit func(*Response) error, pages, count *int) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		index int
+		resp  *Response
+		err   error
+	}
+	results := make(chan result)
+	// Every in-flight or unconsumed page holds a slot, bounding both the
+	// requests and the pages buffered for reassembly.
+	slots := make(chan struct{}, c.Concurrency)
+	var wg sync.WaitGroup
+	go func() {
+		defer wg.Wait()
+		for index := 1; ; index++ {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			wg.Add(1)
+			go func(index int) {
+				defer wg.Done()
+				resp, err := c.page(ctx, withSkip(first, index*pageSize))
+				select {
+				case results <- result{index: index, resp: resp, err: err}:
+				case <-ctx.Done():
+				}
+			}(index)
+		}
+	}()
+
+	pending := map[int]result{}
+	for want := 1; ; {
+		r, ok := pending[want]
+		if !ok {
+			select {
+			case r = <-results:
+			case <-ctx.Done():
+				return &PartialResultError{Pages: *pages, Items: *count, Err: ctx.Err()}
+			}
+			if r.index != want {
+				pending[r.index] = r
+				continue
+			}
+		}
+		delete(pending, want)
+		<-slots
+		if r.err != nil {
+			return &PartialResultError{Pages: *pages, Items: *count, Err: r.err}
+		}
+		*pages++
+		if err := emit(r.resp); err != nil {
+			return err
+		}
+		if c.Progress != nil {
+			c.Progress(*pages, *count, false)
+		}
+		if r.resp.NextPageLink == "" || len(r.resp.Items) == 0 {
+			return nil
+		}
+		want++
+	}
+}
+
+func withSkip(pageURL string, skip int) string {
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return pageURL
+	}
+	q := u.Query()
+	q.Set("$skip", strconv.Itoa(skip))
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+func (c *Client) firstPage(filter Filter) string {
+	params := url.Values{}
+	if c.APIVersion != "" {
+		params.Set("api-version", c.APIVersion)
+	}
+	if c.Currency != "" {
+		params.Set("currencyCode", "'"+c.Currency+"'")
+	}
+	if filter != nil && filter.String() != "" {
+		params.Set("$filter", filter.String())
+	}
+	return c.BaseURL + "?" + params.Encode()
+}
+
+// page fetches a single page, retrying throttled and transient failures.
+func (c *Client) page(ctx context.Context, pageURL string) (*Response, error) {
+	delay := c.Backoff
+	for attempt := 0; ; attempt++ {
+		if err := c.waitPause(ctx); err != nil {
+			return nil, err
+		}
+		resp, err := c.get(ctx, pageURL)
+		if err == nil {
+			return resp, nil
+		}
+		// Transport failures are retried like throttling, unless we were cancelled.
+		retryable := ctx.Err() == nil
+		wait := delay
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) {
+			retryable = retryable && statusErr.Temporary()
+			if statusErr.RetryAfter > 0 {
+				wait = statusErr.RetryAfter
+			}
+			if statusErr.StatusCode == http.StatusTooManyRequests {
+				// Throttling applies to the client, so every request waits.
+				c.pause(wait)
+			}
+		}
+		if !retryable || attempt >= c.MaxRetries {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+		delay *= 2
+	}
+}
+
+// pause holds every request of the client for d.
+func (c *Client) pause(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if until := time.Now().Add(d); until.After(c.pausedUntil) {
+		c.pausedUntil = until
+	}
+}
+
+func (c *Client) waitPause(ctx context.Context) error {
+	c.mu.Lock()
+	wait := time.Until(c.pausedUntil)
+	c.mu.Unlock()
+	if wait <= 0 {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(wait):
+		return nil
+	}
+}
+
+func (c *Client) get(ctx context.Context, pageURL string) (*Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, &StatusError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Body:       string(bodyBytes),
+			RetryAfter: retryAfter(resp.Header.Get("Retry-After")),
+		}
+	}
+	var page Response
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// retryAfter parses a Retry-After header given either in seconds or as an HTTP date.
+func retryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(at); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
@@ -0,0 +1,262 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// pagedServer serves total items, pageSize per page, addressed with $skip
+// like the Retail Prices API. handle may answer a request itself by
+// returning true.
+func pagedServer(t *testing.T, total, pageSize int, handle func(w http.ResponseWriter, r *http.Request, skip int) bool) *httptest.Server {
+	t.Helper()
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		skip, _ := strconv.Atoi(r.URL.Query().Get("$skip"))
+		if handle != nil && handle(w, r, skip) {
+			return
+		}
+		var items []string
+		for i := skip; i < total && i < skip+pageSize; i++ {
+			items = append(items, fmt.Sprintf(`{"meterName":"m%d"}`, i))
+		}
+		next := ""
+		if skip+pageSize < total {
+			next = fmt.Sprintf("%s?$skip=%d", srv.URL, skip+pageSize)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"Items":[%s],"NextPageLink":%q}`, strings.Join(items, ","), next)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func testClient(url string) *Client {
+	c := NewClient("USD")
+	c.BaseURL = url
+	c.Backoff = 10 * time.Millisecond
+	c.Concurrency = 1
+	return c
+}
+
+func meterNames(items []Item) []string {
+	names := make([]string, len(items))
+	for i, item := range items {
+		names[i] = item.MeterName
+	}
+	return names
+}
+
+func checkOrder(t *testing.T, items []Item, total int) {
+	t.Helper()
+	if len(items) != total {
+		t.Fatalf("got %d items, want %d: %v", len(items), total, meterNames(items))
+	}
+	for i, item := range items {
+		if want := fmt.Sprintf("m%d", i); item.MeterName != want {
+			t.Fatalf("item %d is %s, want %s: %v", i, item.MeterName, want, meterNames(items))
+		}
+	}
+}
+
+func TestListFollowsNextPageLink(t *testing.T) {
+	var mu sync.Mutex
+	var requests int
+	srv := pagedServer(t, 7, 3, func(w http.ResponseWriter, r *http.Request, skip int) bool {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+		return false
+	})
+	items, err := testClient(srv.URL).List(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkOrder(t, items, 7)
+	if requests != 3 {
+		t.Errorf("got %d requests, want 3", requests)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		value    string
+		min, max time.Duration
+	}{
+		{"", 0, 0},
+		{"0", 0, 0},
+		{"7", 7 * time.Second, 7 * time.Second},
+		{"soon", 0, 0},
+		{time.Now().Add(30 * time.Second).UTC().Format(http.TimeFormat), 28 * time.Second, 30 * time.Second},
+		{time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat), 0, 0},
+	}
+	for _, tt := range tests {
+		if got := retryAfter(tt.value); got < tt.min || got > tt.max {
+			t.Errorf("retryAfter(%q) = %v, want between %v and %v", tt.value, got, tt.min, tt.max)
+		}
+	}
+}
+
+func TestPageRetriesServerErrorsWithBackoff(t *testing.T) {
+	var mu sync.Mutex
+	var arrivals []time.Time
+	srv := pagedServer(t, 2, 2, func(w http.ResponseWriter, r *http.Request, skip int) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		arrivals = append(arrivals, time.Now())
+		if len(arrivals) <= 2 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return true
+		}
+		return false
+	})
+	items, err := testClient(srv.URL).List(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkOrder(t, items, 2)
+	if len(arrivals) != 3 {
+		t.Fatalf("got %d requests, want 3", len(arrivals))
+	}
+	// The backoff starts at 10ms and doubles.
+	if d := arrivals[1].Sub(arrivals[0]); d < 10*time.Millisecond {
+		t.Errorf("first retry after %v, want at least 10ms", d)
+	}
+	if d := arrivals[2].Sub(arrivals[1]); d < 20*time.Millisecond {
+		t.Errorf("second retry after %v, want at least 20ms", d)
+	}
+}
+
+func TestPageGivesUpOnClientErrors(t *testing.T) {
+	var requests int
+	srv := pagedServer(t, 2, 2, func(w http.ResponseWriter, r *http.Request, skip int) bool {
+		requests++
+		http.Error(w, "bad filter", http.StatusBadRequest)
+		return true
+	})
+	_, err := testClient(srv.URL).List(context.Background(), nil)
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("got %v, want a 400 StatusError", err)
+	}
+	if requests != 1 {
+		t.Errorf("got %d requests, want 1", requests)
+	}
+}
+
+func TestThrottlingPausesTheWholeClient(t *testing.T) {
+	start := time.Now()
+	var mu sync.Mutex
+	throttled := false
+	var otherArrival time.Time
+	srv := pagedServer(t, 2, 2, func(w http.ResponseWriter, r *http.Request, skip int) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Query().Get("other") != "" {
+			otherArrival = time.Now()
+			return false
+		}
+		if !throttled {
+			throttled = true
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "slow down", http.StatusTooManyRequests)
+			return true
+		}
+		return false
+	})
+	c := testClient(srv.URL)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if _, err := c.page(context.Background(), srv.URL); err != nil {
+			t.Error(err)
+		}
+	}()
+	// Let the throttled request come back before sending another one.
+	for {
+		mu.Lock()
+		done := throttled
+		mu.Unlock()
+		if done {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if _, err := c.page(context.Background(), srv.URL+"?other=1"); err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+	if d := otherArrival.Sub(start); d < 900*time.Millisecond {
+		t.Errorf("other request sent %v after the 429, want it held for the Retry-After second", d)
+	}
+}
+
+func TestEachConcurrentReassemblesPagesInOrder(t *testing.T) {
+	const total, pageSize = 40, 4
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	srv := pagedServer(t, total, pageSize, func(w http.ResponseWriter, r *http.Request, skip int) bool {
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+		// Later pages answer first.
+		time.Sleep(time.Duration(total-skip) * time.Millisecond / 2)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		return false
+	})
+	c := testClient(srv.URL)
+	c.Concurrency = 4
+	items, err := c.List(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkOrder(t, items, total)
+	if maxInFlight > c.Concurrency {
+		t.Errorf("%d requests in flight, want at most %d", maxInFlight, c.Concurrency)
+	}
+}
+
+func TestPartialResultWhenAPageFails(t *testing.T) {
+	for _, concurrency := range []int{1, 4} {
+		t.Run(fmt.Sprintf("concurrency %d", concurrency), func(t *testing.T) {
+			srv := pagedServer(t, 20, 2, func(w http.ResponseWriter, r *http.Request, skip int) bool {
+				if skip == 6 {
+					http.Error(w, "broken", http.StatusInternalServerError)
+					return true
+				}
+				return false
+			})
+			c := testClient(srv.URL)
+			c.Concurrency = concurrency
+			c.MaxRetries = 1
+			items, err := c.List(context.Background(), nil)
+			var partial *PartialResultError
+			if !errors.As(err, &partial) {
+				t.Fatalf("got %v, want a PartialResultError", err)
+			}
+			if partial.Pages != 3 || partial.Items != 6 {
+				t.Errorf("got %d pages and %d items, want 3 and 6", partial.Pages, partial.Items)
+			}
+			checkOrder(t, items, 6)
+			var statusErr *StatusError
+			if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusInternalServerError {
+				t.Errorf("got %v, want it to wrap the 500", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package utils
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// The VM price types compared side by side.
+const (
+	PayAsYouGo  = "Consumption"
+	Spot        = "Spot"
+	LowPriority = "Low Priority"
+	Reserved1Y  = "1 Year"
+	Reserved3Y  = "3 Years"
+)
+
+// PriceTypes lists the VM price types in display order.
+var PriceTypes = []string{PayAsYouGo, Spot, LowPriority, Reserved1Y, Reserved3Y}
+
+// The operating systems VM prices are for.
+const (
+	Linux   = "Linux"
+	Windows = "Windows"
+)
+
+// IsWindows reports whether a VM price includes the Windows licence.
+func IsWindows(item Item) bool {
+	return strings.Contains(item.ProductName, "Windows")
+}
+
+// OSOf classifies a VM price item as Linux or Windows, or "" for items of
+// other services.
+func OSOf(item Item) string {
+	switch {
+	case item.ServiceName != "Virtual Machines":
+		return ""
+	case IsWindows(item):
+		return Windows
+	}
+	return Linux
+}
+
+// LicenceKey identifies a VM price regardless of its operating system, for
+// the Windows price of a meter to find its Linux price.
+func LicenceKey(item Item) string {
+	return strings.Join([]string{item.ArmRegionName, item.ArmSkuName, item.MeterName, item.Type, item.ReservationTerm}, "|")
+}
+
+// PriceTypeOf classifies a VM price item as one of PriceTypes, or "" for
+// items that are neither (e.g. DevTest prices).
+func PriceTypeOf(item Item) string {
+	meter := strings.ToLower(item.MeterName)
+	switch {
+	case item.Type == "Reservation":
+		return item.ReservationTerm
+	case item.Type != "Consumption":
+		return ""
+	case strings.Contains(meter, "spot"):
+		return Spot
+	case strings.Contains(meter, "low priority"):
+		return LowPriority
+	}
+	return PayAsYouGo
+}
+
+// MonthlyCost returns what an hourly item costs for a month, or a reservation
+// amortized over the months of its term.
+func MonthlyCost(item Item) (float64, bool) {
+	if item.Type == "Reservation" {
+		months := TermMonths(item.ReservationTerm)
+		if months == 0 {
+			return 0, false
+		}
+		return item.RetailPrice / float64(months), true
+	}
+	unit, err := ParseUnit(item.UnitOfMeasure)
+	if err != nil {
+		return 0, false
+	}
+	return MonthlyPrice(unit, item.RetailPrice, 0)
+}
+
+// PriceTable pivots the monthly cost of items by row (a region, a SKU...) and
+// price type, keeping the cheapest item of every cell.
+type PriceTable struct {
+	Rows  []string
+	cells map[string]map[string]float64
+	items map[string]Item
+}
+
+// NewPriceTable returns an empty table.
+func NewPriceTable() *PriceTable {
+	return &PriceTable{cells: map[string]map[string]float64{}, items: map[string]Item{}}
+}
+
+// Add places item in row, under its price type. Items without a price type
+// or a monthly cost are ignored.
+func (t *PriceTable) Add(row string, item Item) {
+	priceType := PriceTypeOf(item)
+	monthly, ok := MonthlyCost(item)
+	if priceType == "" || !ok || item.TierMinimumUnits > 0 {
+		return
+	}
+	cells, ok := t.cells[row]
+	if !ok {
+		cells = map[string]float64{}
+		t.cells[row] = cells
+		t.Rows = append(t.Rows, row)
+		t.items[row] = item
+	}
+	if current, ok := cells[priceType]; !ok || monthly < current {
+		cells[priceType] = monthly
+	}
+}
+
+// Cost returns the monthly cost of a cell.
+func (t *PriceTable) Cost(row, priceType string) (float64, bool) {
+	cost, ok := t.cells[row][priceType]
+	return cost, ok
+}
+
+// Set replaces the cost of a cell of an existing row.
+func (t *PriceTable) Set(row, priceType string, cost float64) {
+	if cells, ok := t.cells[row]; ok {
+		cells[priceType] = cost
+	}
+}
+
+// Item returns an item of row, to describe it.
+func (t *PriceTable) Item(row string) Item {
+	return t.items[row]
+}
+
+// Normalize returns a table of the costs divided by the capacity of their
+// row, leaving out the rows without one.
+func (t *PriceTable) Normalize(capacity func(row string) (float64, bool)) *PriceTable {
+	normalized := NewPriceTable()
+	for _, row := range t.Rows {
+		c, ok := capacity(row)
+		if !ok {
+			continue
+		}
+		cells := map[string]float64{}
+		for priceType, cost := range t.cells[row] {
+			cells[priceType] = cost / c
+		}
+		normalized.Rows = append(normalized.Rows, row)
+		normalized.cells[row] = cells
+		normalized.items[row] = t.items[row]
+	}
+	return normalized
+}
+
+// Cheapest returns the row with the lowest cost of priceType, or "".
+func (t *PriceTable) Cheapest(priceType string) string {
+	cheapest, lowest := "", math.Inf(1)
+	for _, row := range t.Rows {
+		if cost, ok := t.Cost(row, priceType); ok && cost < lowest {
+			cheapest, lowest = row, cost
+		}
+	}
+	return cheapest
+}
+
+// SortBy orders the rows by the cost of the first price type they have, rows
+// without any cost last.
+func (t *PriceTable) SortBy(priceTypes ...string) {
+	key := func(row string) float64 {
+		for _, priceType := range priceTypes {
+			if cost, ok := t.Cost(row, priceType); ok {
+				return cost
+			}
+		}
+		return math.Inf(1)
+	}
+	sort.SliceStable(t.Rows, func(i, j int) bool {
+		return key(t.Rows[i]) < key(t.Rows[j])
+	})
+}
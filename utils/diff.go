@@ -0,0 +1,322 @@
+package utils
+
+import (
+	"bufio"
+	"container/heap"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PriceKey identifies a price across snapshots: the meter and SKU, the price
+// type and reservation term, the region and the tier.
+func PriceKey(item Item) string {
+	return MeterKey(item) + "|" + strconv.FormatFloat(item.TierMinimumUnits, 'f', -1, 64)
+}
+
+// Kinds of PriceChange.
+const (
+	PriceAdded     = "added"
+	PriceRemoved   = "removed"
+	PriceIncreased = "increased"
+	PriceDecreased = "decreased"
+)
+
+// PriceChange is a difference between two snapshots. Old is nil for added
+// prices and New for removed ones.
+type PriceChange struct {
+	Kind string
+	Old  *Item
+	New  *Item
+}
+
+// Percent returns the change of the retail price in percent. It is not
+// defined, and ok is false, for added or removed prices and for a price
+// going up from zero.
+func (c PriceChange) Percent() (percent float64, ok bool) {
+	if c.Old == nil || c.New == nil || c.Old.RetailPrice == 0 {
+		return 0, false
+	}
+	return (c.New.RetailPrice - c.Old.RetailPrice) / c.Old.RetailPrice * 100, true
+}
+
+// Item returns the newest version of the changed item.
+func (c PriceChange) Item() Item {
+	if c.New != nil {
+		return *c.New
+	}
+	return *c.Old
+}
+
+// DiffOptions configure DiffSnapshots.
+type DiffOptions struct {
+	// Filter restricts the comparison to matching items, nil compares everything.
+	Filter Filter
+	// ChunkSize is the number of items sorted in memory at once.
+	ChunkSize int
+	// TempDir holds the sorted runs, the system temp dir when empty.
+	TempDir string
+}
+
+// DiffSnapshots calls fn for every price added, removed or changed between
+// the snapshot files oldPath and newPath, in PriceKey order. Both snapshots
+// are sorted by key on disk in chunks and merged, so memory use does not
+// depend on their size.
+func DiffSnapshots(ctx context.Context, oldPath, newPath string, opts DiffOptions, fn func(PriceChange) error) error {
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = 100000
+	}
+	tmpDir, err := os.MkdirTemp(opts.TempDir, "cloudcost-diff-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	oldRuns, err := sortRuns(ctx, oldPath, filepath.Join(tmpDir, "old"), opts)
+	if err != nil {
+		return err
+	}
+	newRuns, err := sortRuns(ctx, newPath, filepath.Join(tmpDir, "new"), opts)
+	if err != nil {
+		return err
+	}
+	oldItems, err := newRunMerger(oldRuns)
+	if err != nil {
+		return err
+	}
+	defer oldItems.Close()
+	newItems, err := newRunMerger(newRuns)
+	if err != nil {
+		return err
+	}
+	defer newItems.Close()
+
+	oldRecord, oldOK, err := oldItems.Next()
+	if err != nil {
+		return err
+	}
+	newRecord, newOK, err := newItems.Next()
+	if err != nil {
+		return err
+	}
+	for oldOK || newOK {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var change *PriceChange
+		switch {
+		case !newOK || (oldOK && oldRecord.key < newRecord.key):
+			old, err := oldRecord.item()
+			if err != nil {
+				return err
+			}
+			change = &PriceChange{Kind: PriceRemoved, Old: &old}
+			oldRecord, oldOK, err = oldItems.Next()
+			if err != nil {
+				return err
+			}
+		case !oldOK || newRecord.key < oldRecord.key:
+			item, err := newRecord.item()
+			if err != nil {
+				return err
+			}
+			change = &PriceChange{Kind: PriceAdded, New: &item}
+			newRecord, newOK, err = newItems.Next()
+			if err != nil {
+				return err
+			}
+		default:
+			old, err := oldRecord.item()
+			if err != nil {
+				return err
+			}
+			item, err := newRecord.item()
+			if err != nil {
+				return err
+			}
+			if item.RetailPrice > old.RetailPrice {
+				change = &PriceChange{Kind: PriceIncreased, Old: &old, New: &item}
+			} else if item.RetailPrice < old.RetailPrice {
+				change = &PriceChange{Kind: PriceDecreased, Old: &old, New: &item}
+			}
+			if oldRecord, oldOK, err = oldItems.Next(); err != nil {
+				return err
+			}
+			if newRecord, newOK, err = newItems.Next(); err != nil {
+				return err
+			}
+		}
+		if change != nil {
+			if err := fn(*change); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// record is a line of a sorted run: the PriceKey, a tab and the item as JSON.
+type record struct {
+	key  string
+	line string
+}
+
+func (r record) item() (Item, error) {
+	var item Item
+	err := json.Unmarshal([]byte(r.line[len(r.key)+1:]), &item)
+	return item, err
+}
+
+// sortRuns writes the items of a snapshot matching opts.Filter to files of at
+// most opts.ChunkSize records, each sorted by key. Only the first item of a
+// key is kept within a chunk.
+func sortRuns(ctx context.Context, path, prefix string, opts DiffOptions) ([]string, error) {
+	s, err := OpenSnapshot(path)
+	if err != nil {
+		return nil, err
+	}
+	var runs []string
+	chunk := make([]record, 0, opts.ChunkSize)
+	flush := func() error {
+		if len(chunk) == 0 {
+			return nil
+		}
+		sort.SliceStable(chunk, func(i, j int) bool { return chunk[i].key < chunk[j].key })
+		name := fmt.Sprintf("%s-%d", prefix, len(runs))
+		f, err := os.Create(name)
+		if err != nil {
+			return err
+		}
+		w := bufio.NewWriter(f)
+		for i, r := range chunk {
+			if i > 0 && chunk[i-1].key == r.key {
+				continue
+			}
+			w.WriteString(r.line)
+			w.WriteByte('\n')
+		}
+		err = w.Flush()
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		runs = append(runs, name)
+		chunk = chunk[:0]
+		return err
+	}
+	err = s.Scan(func(item Item) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if opts.Filter != nil && !opts.Filter.Match(item) {
+			return nil
+		}
+		data, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+		key := PriceKey(item)
+		chunk = append(chunk, record{key: key, line: key + "\t" + string(data)})
+		if len(chunk) == opts.ChunkSize {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return runs, flush()
+}
+
+// runMerger merges sorted runs, yielding records in key order and dropping
+// records whose key was already yielded.
+type runMerger struct {
+	files []*os.File
+	heap  runHeap
+	last  *string
+}
+
+type runCursor struct {
+	scanner *bufio.Scanner
+	current record
+}
+
+type runHeap []*runCursor
+
+func (h runHeap) Len() int           { return len(h) }
+func (h runHeap) Less(i, j int) bool { return h[i].current.key < h[j].current.key }
+func (h runHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *runHeap) Push(x any)        { *h = append(*h, x.(*runCursor)) }
+func (h *runHeap) Pop() any {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
+
+func newRunMerger(runs []string) (*runMerger, error) {
+	m := &runMerger{}
+	for _, run := range runs {
+		f, err := os.Open(run)
+		if err != nil {
+			m.Close()
+			return nil, err
+		}
+		m.files = append(m.files, f)
+		c := &runCursor{scanner: bufio.NewScanner(f)}
+		c.scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+		ok, err := c.advance()
+		if err != nil {
+			m.Close()
+			return nil, err
+		}
+		if ok {
+			m.heap = append(m.heap, c)
+		}
+	}
+	heap.Init(&m.heap)
+	return m, nil
+}
+
+func (c *runCursor) advance() (bool, error) {
+	if !c.scanner.Scan() {
+		return false, c.scanner.Err()
+	}
+	line := c.scanner.Text()
+	key, _, _ := strings.Cut(line, "\t")
+	c.current = record{key: key, line: line}
+	return true, nil
+}
+
+// Next returns the next record, or false once every run is exhausted.
+func (m *runMerger) Next() (record, bool, error) {
+	for m.heap.Len() > 0 {
+		c := m.heap[0]
+		r := c.current
+		ok, err := c.advance()
+		if err != nil {
+			return record{}, false, err
+		}
+		if ok {
+			heap.Fix(&m.heap, 0)
+		} else {
+			heap.Pop(&m.heap)
+		}
+		if m.last != nil && *m.last == r.key {
+			continue
+		}
+		m.last = &r.key
+		return r, true, nil
+	}
+	return record{}, false, nil
+}
+
+func (m *runMerger) Close() {
+	for _, f := range m.files {
+		f.Close()
+	}
+}
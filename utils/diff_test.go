@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeSnapshot writes items to a snapshot file in dir.
+func writeSnapshot(t *testing.T, dir, name string, items []Item) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := gzip.NewWriter(f)
+	enc := json.NewEncoder(zw)
+	if err := enc.Encode(SnapshotHeader{Version: SnapshotVersion, Currency: "USD", Items: len(items)}); err != nil {
+		t.Fatal(err)
+	}
+	for _, item := range items {
+		if err := enc.Encode(item); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	price := func(meter string, tier, retail float64) Item {
+		return Item{MeterID: meter, SkuID: "sku", Type: "Consumption", ArmRegionName: "westeurope", TierMinimumUnits: tier, RetailPrice: retail}
+	}
+	dir := t.TempDir()
+	oldPath := writeSnapshot(t, dir, "old.jsonl.gz", []Item{
+		price("unchanged", 0, 1),
+		price("removed", 0, 2),
+		price("up", 0, 10),
+		price("down", 0, 10),
+		price("tiered", 0, 0),
+		price("tiered", 100, 0.5),
+		price("fromzero", 0, 0),
+	})
+	newPath := writeSnapshot(t, dir, "new.jsonl.gz", []Item{
+		price("fromzero", 0, 3),
+		price("tiered", 100, 0.25),
+		price("tiered", 0, 0),
+		price("down", 0, 5),
+		price("added", 0, 7),
+		price("up", 0, 12),
+		price("unchanged", 0, 1),
+	})
+
+	type change struct {
+		kind, meter string
+		tier        float64
+		percent     float64
+		ok          bool
+	}
+	want := []change{
+		{PriceAdded, "added", 0, 0, false},
+		{PriceDecreased, "down", 0, -50, true},
+		{PriceIncreased, "fromzero", 0, 0, false},
+		{PriceRemoved, "removed", 0, 0, false},
+		{PriceDecreased, "tiered", 100, -50, true},
+		{PriceIncreased, "up", 0, 20, true},
+	}
+	// A chunk size of 2 sorts each snapshot into several runs to merge.
+	for _, chunkSize := range []int{2, 100} {
+		var got []change
+		err := DiffSnapshots(context.Background(), oldPath, newPath, DiffOptions{ChunkSize: chunkSize, TempDir: dir}, func(c PriceChange) error {
+			percent, ok := c.Percent()
+			item := c.Item()
+			got = append(got, change{c.Kind, item.MeterID, item.TierMinimumUnits, percent, ok})
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("chunk size %d: changes %+v, want %+v", chunkSize, got, want)
+		}
+	}
+}
+
+func TestPriceKey(t *testing.T) {
+	base := Item{MeterID: "m", SkuID: "s", Type: "Consumption", ArmRegionName: "westeurope"}
+	tier := base
+	tier.TierMinimumUnits = 100
+	reserved := base
+	reserved.Type, reserved.ReservationTerm = "Reservation", "1 Year"
+	repriced := base
+	repriced.RetailPrice = 5
+	if PriceKey(base) == PriceKey(tier) || PriceKey(base) == PriceKey(reserved) {
+		t.Error("tiers and price types of a meter share a key")
+	}
+	if PriceKey(base) != PriceKey(repriced) {
+		t.Error("the key of a meter depends on its price")
+	}
+}
@@ -0,0 +1,357 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Spec is a bill of materials: a list of resources priced together. Values
+// may reference variables as ${name}; environments override variables,
+// the default region and individual resources by name.
+type Spec struct {
+	Currency     string                 `yaml:"currency"`
+	Region       string                 `yaml:"region"`
+	Variables    map[string]string      `yaml:"variables"`
+	Environments map[string]Environment `yaml:"environments"`
+	Resources    []Resource             `yaml:"resources"`
+}
+
+// Environment holds the overrides of one environment of a spec.
+type Environment struct {
+	Region    string               `yaml:"region"`
+	Variables map[string]string    `yaml:"variables"`
+	Resources map[string]yaml.Node `yaml:"resources"`
+}
+
+// Resource is a line of a bill of materials.
+type Resource struct {
+	Name string `yaml:"name"`
+	// Service is matched against serviceName, e.g. "Virtual Machines".
+	Service string `yaml:"service"`
+	// SKU is matched against armSkuName or skuName.
+	SKU string `yaml:"sku"`
+	// Product and Meter narrow the match to items whose productName or
+	// meterName contain them, ExcludeProduct drops items whose productName
+	// contains it.
+	Product        string `yaml:"product"`
+	Meter          string `yaml:"meter"`
+	ExcludeProduct string `yaml:"excludeProduct"`
+	Region         string `yaml:"region"`
+	PriceType      string `yaml:"priceType"`
+	// Term picks the reservation or savings plan term, e.g. "1 Year".
+	Term     string   `yaml:"term"`
+	Quantity *float64 `yaml:"quantity"`
+	Usage    Usage    `yaml:"usage"`
+}
+
+// Usage is the monthly consumption of a resource. Hours default to a full month.
+type Usage struct {
+	Hours        *float64 `yaml:"hours"`
+	GB           float64  `yaml:"gb"`
+	Transactions float64  `yaml:"transactions"`
+}
+
+var variablePattern = regexp.MustCompile(`\$\{([A-Za-z0-9_.-]+)\}`)
+
+// LoadSpec reads a YAML or JSON spec, applying the environment env (if not
+// empty) and the variables in overrides.
+func LoadSpec(path string, env string, overrides map[string]string) (*Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseSpec(data, env, overrides)
+}
+
+// ParseSpec parses a YAML or JSON spec. See LoadSpec.
+func ParseSpec(data []byte, env string, overrides map[string]string) (*Spec, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	// Collect variables first, then substitute them before decoding the spec
+	// so that they can be used in numeric fields too.
+	var raw struct {
+		Variables    map[string]string `yaml:"variables"`
+		Environments map[string]struct {
+			Variables map[string]string `yaml:"variables"`
+		} `yaml:"environments"`
+	}
+	if err := root.Decode(&raw); err != nil {
+		return nil, err
+	}
+	variables := map[string]string{}
+	for k, v := range raw.Variables {
+		variables[k] = v
+	}
+	if env != "" {
+		environment, ok := raw.Environments[env]
+		if !ok {
+			return nil, fmt.Errorf("unknown environment %q", env)
+		}
+		for k, v := range environment.Variables {
+			variables[k] = v
+		}
+	}
+	for k, v := range overrides {
+		variables[k] = v
+	}
+
+	if err := substitute(&root, variables); err != nil {
+		return nil, err
+	}
+	var spec Spec
+	if err := root.Decode(&spec); err != nil {
+		return nil, err
+	}
+	if env == "" {
+		return &spec, nil
+	}
+
+	environment := spec.Environments[env]
+	if environment.Region != "" {
+		spec.Region = environment.Region
+	}
+	for name, override := range environment.Resources {
+		found := false
+		for i := range spec.Resources {
+			if spec.Resources[i].Name == name {
+				// Decoding into the resource only replaces the fields present in the override.
+				if err := override.Decode(&spec.Resources[i]); err != nil {
+					return nil, fmt.Errorf("environment %s: resource %s: %w", env, name, err)
+				}
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("environment %s overrides unknown resource %q", env, name)
+		}
+	}
+	return &spec, nil
+}
+
+// substitute replaces ${name} references in the scalars of node.
+func substitute(node *yaml.Node, variables map[string]string) error {
+	if node.Kind == yaml.ScalarNode && strings.Contains(node.Value, "${") {
+		var missing string
+		value := variablePattern.ReplaceAllStringFunc(node.Value, func(ref string) string {
+			name := variablePattern.FindStringSubmatch(ref)[1]
+			v, ok := variables[name]
+			if !ok {
+				missing = name
+			}
+			return v
+		})
+		if missing != "" {
+			return fmt.Errorf("line %d: undefined variable %q", node.Line, missing)
+		}
+		// Let the substituted value resolve to a number or a bool if it is one.
+		node.Value, node.Tag, node.Style = value, "", 0
+	}
+	for _, child := range node.Content {
+		if err := substitute(child, variables); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Line is a priced resource. Item is nil when the resource could not be priced.
+type Line struct {
+	Resource Resource
+	Item     *Item
+	Monthly  float64
+	Err      error
+}
+
+// Estimator prices resources against the Retail Prices API or a snapshot.
+type Estimator struct {
+	Prices Source
+	// Region is used for resources that do not set one.
+	Region string
+}
+
+// Estimate prices every resource. Resources that cannot be priced are
+// returned with Err set rather than failing the whole estimate.
+func (e *Estimator) Estimate(ctx context.Context, resources []Resource) ([]Line, error) {
+	lines := make([]Line, 0, len(resources))
+	for _, r := range resources {
+		line, err := e.Price(ctx, r)
+		if err != nil {
+			return lines, err
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+// Price resolves a resource to a meter and computes its monthly cost over
+// the meter's tiers. Resources without a region are priced in the
+// estimator's. An error is only returned when the API could not be queried.
+func (e *Estimator) Price(ctx context.Context, r Resource) (Line, error) {
+	if r.Region == "" {
+		r.Region = e.Region
+	}
+	line := Line{Resource: r}
+	if r.Service == "" && r.SKU == "" && r.Product == "" && r.Meter == "" {
+		line.Err = fmt.Errorf("resource needs at least one of service, sku, product or meter")
+		return line, nil
+	}
+	if r.Region == "" {
+		line.Err = fmt.Errorf("no region: set one on the resource, in the file or with --region")
+		return line, nil
+	}
+	items, err := ListItems(ctx, e.Prices, r.Filter())
+	if err != nil {
+		return line, fmt.Errorf("%s: %w", r.Name, err)
+	}
+	tiers, err := selectMeter(r, items)
+	if err != nil {
+		line.Err = err
+		return line, nil
+	}
+	item := &tiers[0]
+	unit, err := ParseUnit(item.UnitOfMeasure)
+	if err != nil {
+		line.Err = err
+		return line, nil
+	}
+	// A usage the meter bills by must be given rather than priced at 0.
+	switch {
+	case (unit.Dimension == Data || unit.Dimension == DataTime) && r.Usage.GB == 0:
+		line.Err = fmt.Errorf("set usage.gb: %s is billed per %s", item.MeterName, item.UnitOfMeasure)
+		return line, nil
+	case unit.Dimension == Count && r.Usage.Transactions == 0:
+		line.Err = fmt.Errorf("set usage.transactions: %s is billed per %s", item.MeterName, item.UnitOfMeasure)
+		return line, nil
+	}
+	line.Item = item
+	if len(tiers) == 1 && item.TierMinimumUnits == 0 {
+		line.Monthly = r.MonthlyCost(unit, *item)
+		return line, nil
+	}
+	// Tiers apply to the usage of every instance together.
+	consumption := r.Consumption()
+	consumption = consumption.WithQuantity(unit, consumption.Quantity(unit)*r.quantity())
+	billed, err := TieredCost(tiers, consumption)
+	if err != nil {
+		line.Err = err
+		return line, nil
+	}
+	for _, tier := range billed {
+		line.Monthly += tier.Cost
+	}
+	return line, nil
+}
+
+// Filter returns the Retail Prices filter matching the resource.
+func (r Resource) Filter() Filter {
+	priceType := r.PriceType
+	if priceType == "" {
+		priceType = "Consumption"
+	}
+	filters := []Filter{Eq("priceType", priceType)}
+	if r.Service != "" {
+		filters = append(filters, Eq("serviceName", r.Service))
+	}
+	if r.SKU != "" {
+		filters = append(filters, Or(Eq("armSkuName", r.SKU), Eq("skuName", r.SKU)))
+	}
+	if r.Region != "" {
+		filters = append(filters, Eq("armRegionName", r.Region))
+	}
+	if r.Product != "" {
+		filters = append(filters, Contains("productName", r.Product))
+	}
+	if r.Meter != "" {
+		filters = append(filters, Contains("meterName", r.Meter))
+	}
+	return And(filters...)
+}
+
+// MonthlyCost applies the resource's usage and quantity to item.
+func (r Resource) MonthlyCost(unit Unit, item Item) float64 {
+	if item.Type == "Reservation" {
+		if months := TermMonths(item.ReservationTerm); months > 0 {
+			return item.RetailPrice / float64(months) * r.quantity()
+		}
+	}
+	return Cost(unit, item.RetailPrice, r.Consumption()) * r.quantity()
+}
+
+// Consumption returns the monthly usage of one unit of the resource.
+func (r Resource) Consumption() Consumption {
+	hours := float64(HoursPerMonth)
+	if r.Usage.Hours != nil {
+		hours = *r.Usage.Hours
+	}
+	return Consumption{Hours: hours, GB: r.Usage.GB, Count: r.Usage.Transactions}
+}
+
+func (r Resource) quantity() float64 {
+	if r.Quantity != nil {
+		return *r.Quantity
+	}
+	return 1
+}
+
+// selectMeter returns the items of the meter pricing r, one per tier, lowest
+// first. Spot and Low Priority meters are only considered when asked for.
+// Anything but a single matching meter is an error, so that ambiguous
+// resources are narrowed with product, meter, excludeProduct or term rather
+// than priced at a guess.
+func selectMeter(r Resource, items []Item) ([]Item, error) {
+	wants := strings.ToLower(r.SKU + " " + r.Meter)
+	meters := map[string][]Item{}
+	var keys []string
+	for _, item := range items {
+		meter := strings.ToLower(item.MeterName)
+		if strings.Contains(meter, "spot") && !strings.Contains(wants, "spot") {
+			continue
+		}
+		if strings.Contains(meter, "low priority") && !strings.Contains(wants, "low priority") {
+			continue
+		}
+		if r.ExcludeProduct != "" && strings.Contains(strings.ToLower(item.ProductName), strings.ToLower(r.ExcludeProduct)) {
+			continue
+		}
+		if r.Term != "" && TermMonths(item.ReservationTerm) != TermMonths(r.Term) {
+			continue
+		}
+		key := MeterKey(item)
+		if _, ok := meters[key]; !ok {
+			keys = append(keys, key)
+		}
+		meters[key] = append(meters[key], item)
+	}
+	switch len(keys) {
+	case 0:
+		return nil, fmt.Errorf("no price found for %s", r.Filter())
+	case 1:
+		tiers := meters[keys[0]]
+		sort.SliceStable(tiers, func(i, j int) bool {
+			return tiers[i].TierMinimumUnits < tiers[j].TierMinimumUnits
+		})
+		return tiers, nil
+	}
+	var names []string
+	for _, key := range keys {
+		item := meters[key][0]
+		name := fmt.Sprintf("%s (%s)", item.MeterName, item.ProductName)
+		if item.ReservationTerm != "" {
+			name += " " + item.ReservationTerm
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(names) > 5 {
+		names = append(names[:5], "...")
+	}
+	return nil, fmt.Errorf("ambiguous: %d meters match (%s), narrow it with product, meter, excludeProduct or term", len(keys), strings.Join(names, ", "))
+}
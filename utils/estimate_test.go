@@ -0,0 +1,157 @@
+package utils
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+)
+
+// fakeSource serves its items through their filter, like a snapshot.
+type fakeSource []Item
+
+func (s fakeSource) Each(ctx context.Context, filter Filter, fn func(Item) error) error {
+	for _, item := range s {
+		if filter != nil && !filter.Match(item) {
+			continue
+		}
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestParseSpec(t *testing.T) {
+	data := []byte(`
+region: westeurope
+variables:
+  size: Standard_D2s_v5
+  count: "2"
+environments:
+  prod:
+    region: northeurope
+    variables:
+      size: Standard_D4s_v5
+    resources:
+      db:
+        quantity: 3
+resources:
+  - name: app
+    service: Virtual Machines
+    sku: ${size}
+    quantity: ${count}
+  - name: db
+    sku: ${size}
+    excludeProduct: Windows
+    quantity: 1
+`)
+	tests := []struct {
+		name      string
+		env       string
+		overrides map[string]string
+		region    string
+		appSKU    string
+		appCount  float64
+		dbCount   float64
+	}{
+		{"defaults", "", nil, "westeurope", "Standard_D2s_v5", 2, 1},
+		{"environment", "prod", nil, "northeurope", "Standard_D4s_v5", 2, 3},
+		{"overrides win over the environment", "prod", map[string]string{"size": "Standard_E4s_v5", "count": "5"}, "northeurope", "Standard_E4s_v5", 5, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, err := ParseSpec(data, tt.env, tt.overrides)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if spec.Region != tt.region {
+				t.Errorf("region %q, want %q", spec.Region, tt.region)
+			}
+			app, db := spec.Resources[0], spec.Resources[1]
+			if app.SKU != tt.appSKU || db.SKU != tt.appSKU {
+				t.Errorf("skus %q and %q, want %q", app.SKU, db.SKU, tt.appSKU)
+			}
+			if app.quantity() != tt.appCount || db.quantity() != tt.dbCount {
+				t.Errorf("quantities %g and %g, want %g and %g", app.quantity(), db.quantity(), tt.appCount, tt.dbCount)
+			}
+			// The override only replaces the fields it sets.
+			if db.ExcludeProduct != "Windows" {
+				t.Errorf("db excludeProduct %q, want it kept", db.ExcludeProduct)
+			}
+		})
+	}
+}
+
+func TestParseSpecErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+		env  string
+		want string
+	}{
+		{"undefined variable", "resources:\n  - name: a\n    sku: ${nope}\n", "", `undefined variable "nope"`},
+		{"unknown environment", "resources: []\n", "staging", `unknown environment "staging"`},
+		{"unknown resource", "environments:\n  prod:\n    resources:\n      b:\n        quantity: 2\nresources:\n  - name: a\n", "prod", `unknown resource "b"`},
+	}
+	for _, tt := range tests {
+		if _, err := ParseSpec([]byte(tt.spec), tt.env, nil); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: got %v, want an error containing %s", tt.name, err, tt.want)
+		}
+	}
+}
+
+var estimateItems = fakeSource{
+	{ServiceName: "Virtual Machines", ArmSkuName: "Standard_D2s_v5", ArmRegionName: "westeurope", MeterID: "d2", MeterName: "D2s v5", ProductName: "Virtual Machines Dsv5 Series", UnitOfMeasure: "1 Hour", RetailPrice: 0.1, Type: "Consumption"},
+	{ServiceName: "Virtual Machines", ArmSkuName: "Standard_D2s_v5", ArmRegionName: "westeurope", MeterID: "d2w", MeterName: "D2s v5", ProductName: "Virtual Machines Dsv5 Series Windows", UnitOfMeasure: "1 Hour", RetailPrice: 0.2, Type: "Consumption"},
+	{ServiceName: "Virtual Machines", ArmSkuName: "Standard_D2s_v5", ArmRegionName: "westeurope", MeterID: "d2s", MeterName: "D2s v5 Spot", ProductName: "Virtual Machines Dsv5 Series", UnitOfMeasure: "1 Hour", RetailPrice: 0.02, Type: "Consumption"},
+	{ServiceName: "Virtual Machines", ArmSkuName: "Standard_D2s_v5", ArmRegionName: "westeurope", MeterID: "d2", MeterName: "D2s v5", ProductName: "Virtual Machines Dsv5 Series", UnitOfMeasure: "1 Hour", RetailPrice: 876, Type: "Reservation", ReservationTerm: "1 Year"},
+	{ServiceName: "Virtual Machines", ArmSkuName: "Standard_D2s_v5", ArmRegionName: "westeurope", MeterID: "d2", MeterName: "D2s v5", ProductName: "Virtual Machines Dsv5 Series", UnitOfMeasure: "1 Hour", RetailPrice: 1752, Type: "Reservation", ReservationTerm: "3 Years"},
+	{ServiceName: "Bandwidth", SkuName: "Standard", ArmRegionName: "westeurope", MeterID: "bw", MeterName: "Standard Data Transfer Out", ProductName: "Bandwidth", UnitOfMeasure: "1 GB", RetailPrice: 0, Type: "Consumption"},
+	{ServiceName: "Bandwidth", SkuName: "Standard", ArmRegionName: "westeurope", MeterID: "bw", MeterName: "Standard Data Transfer Out", ProductName: "Bandwidth", UnitOfMeasure: "1 GB", RetailPrice: 0.08, TierMinimumUnits: 100, Type: "Consumption"},
+	{ServiceName: "Log Analytics", SkuName: "Analytics Logs", ArmRegionName: "westeurope", MeterID: "la", MeterName: "Analytics Logs Data Ingestion", ProductName: "Log Analytics", UnitOfMeasure: "1 GB", RetailPrice: 2.5, TierMinimumUnits: 5, Type: "Consumption"},
+}
+
+func TestEstimatorPrice(t *testing.T) {
+	quantity := func(q float64) *float64 { return &q }
+	tests := []struct {
+		name     string
+		resource Resource
+		monthly  float64
+		err      string
+	}{
+		{"hourly times quantity", Resource{SKU: "Standard_D2s_v5", ExcludeProduct: "Windows", Quantity: quantity(2)}, 146, ""},
+		{"excludeProduct ignores case", Resource{SKU: "Standard_D2s_v5", ExcludeProduct: "windows"}, 73, ""},
+		{"spot only when asked for", Resource{SKU: "Standard_D2s_v5", Meter: "Spot"}, 14.6, ""},
+		{"ambiguous OS", Resource{SKU: "Standard_D2s_v5"}, 0, "ambiguous: 2 meters match"},
+		{"reservation term", Resource{SKU: "Standard_D2s_v5", PriceType: "Reservation", Term: "3 Years"}, 48.666667, ""},
+		{"ambiguous term", Resource{SKU: "Standard_D2s_v5", PriceType: "Reservation"}, 0, "ambiguous: 2 meters match"},
+		{"tiers over every instance", Resource{Service: "Bandwidth", Quantity: quantity(2), Usage: Usage{GB: 80}}, 4.8, ""},
+		{"free first tier", Resource{Service: "Log Analytics", Usage: Usage{GB: 50}}, 112.5, ""},
+		{"data without usage", Resource{Service: "Bandwidth"}, 0, "set usage.gb"},
+		{"no region", Resource{SKU: "Standard_D2s_v5", Region: "-"}, 0, "no region"},
+		{"no match", Resource{SKU: "Standard_Z9"}, 0, "no price found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &Estimator{Prices: estimateItems, Region: "westeurope"}
+			if tt.resource.Region == "-" {
+				tt.resource.Region, e.Region = "", ""
+			}
+			line, err := e.Price(context.Background(), tt.resource)
+			if err != nil {
+				t.Fatal(err)
+			}
+			switch {
+			case tt.err != "":
+				if line.Err == nil || !strings.Contains(line.Err.Error(), tt.err) {
+					t.Errorf("got %v, want an error containing %q", line.Err, tt.err)
+				}
+			case line.Err != nil:
+				t.Errorf("unexpected error: %v", line.Err)
+			case math.Abs(line.Monthly-tt.monthly) > 1e-6:
+				t.Errorf("monthly %g, want %g", line.Monthly, tt.monthly)
+			}
+		})
+	}
+}
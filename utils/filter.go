@@ -0,0 +1,215 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Filter is an OData $filter expression understood by the Retail Prices API.
+// Match evaluates it locally, comparing values case-insensitively like the API.
+type Filter interface {
+	String() string
+	Match(item Item) bool
+}
+
+// FilterFields are the item fields the Retail Prices API can filter on.
+var FilterFields = []string{
+	"armRegionName",
+	"location",
+	"meterId",
+	"meterName",
+	"productId",
+	"productName",
+	"skuId",
+	"skuName",
+	"armSkuName",
+	"serviceId",
+	"serviceName",
+	"serviceFamily",
+	"priceType",
+}
+
+// FilterField returns the canonical spelling of a filterable field, matched
+// case-insensitively.
+func FilterField(name string) (string, error) {
+	for _, field := range FilterFields {
+		if strings.EqualFold(field, strings.TrimSpace(name)) {
+			return field, nil
+		}
+	}
+	return "", fmt.Errorf("unknown filter field %q (available: %s)", name, strings.Join(FilterFields, ", "))
+}
+
+type comparison struct {
+	op    string
+	field string
+	value string
+}
+
+func (c comparison) String() string {
+	return fmt.Sprintf("%s %s %s", c.field, c.op, quote(c.value))
+}
+
+func (c comparison) Match(item Item) bool {
+	equal := strings.EqualFold(fieldValue(item, c.field), c.value)
+	if c.op == "ne" {
+		return !equal
+	}
+	return equal
+}
+
+type function struct {
+	name  string
+	field string
+	value string
+}
+
+func (f function) String() string {
+	return fmt.Sprintf("%s(%s, %s)", f.name, f.field, quote(f.value))
+}
+
+func (f function) Match(item Item) bool {
+	value, want := strings.ToLower(fieldValue(item, f.field)), strings.ToLower(f.value)
+	if f.name == "startswith" {
+		return strings.HasPrefix(value, want)
+	}
+	return strings.Contains(value, want)
+}
+
+type group struct {
+	op      string
+	filters []Filter
+}
+
+func (g group) String() string {
+	parts := make([]string, 0, len(g.filters))
+	for _, f := range g.filters {
+		s := f.String()
+		if _, nested := f.(group); nested {
+			s = "(" + s + ")"
+		}
+		parts = append(parts, s)
+	}
+	return strings.Join(parts, " "+g.op+" ")
+}
+
+// Match of an empty group matches every item, like an empty $filter.
+func (g group) Match(item Item) bool {
+	if len(g.filters) == 0 {
+		return true
+	}
+	for _, f := range g.filters {
+		matched := f.Match(item)
+		if g.op == "or" && matched {
+			return true
+		}
+		if g.op == "and" && !matched {
+			return false
+		}
+	}
+	return g.op == "and"
+}
+
+// fieldValue returns the value of a filterable field of item.
+func fieldValue(item Item, field string) string {
+	switch field {
+	case "armRegionName":
+		return item.ArmRegionName
+	case "location":
+		return item.Location
+	case "meterId":
+		return item.MeterID
+	case "meterName":
+		return item.MeterName
+	case "productId":
+		return item.ProductID
+	case "productName":
+		return item.ProductName
+	case "skuId":
+		return item.SkuID
+	case "skuName":
+		return item.SkuName
+	case "armSkuName":
+		return item.ArmSkuName
+	case "serviceId":
+		return item.ServiceID
+	case "serviceName":
+		return item.ServiceName
+	case "serviceFamily":
+		return item.ServiceFamily
+	case "priceType":
+		return item.Type
+	}
+	return ""
+}
+
+// Eq matches items whose field equals value.
+func Eq(field, value string) Filter {
+	return comparison{op: "eq", field: field, value: value}
+}
+
+// Ne matches items whose field does not equal value.
+func Ne(field, value string) Filter {
+	return comparison{op: "ne", field: field, value: value}
+}
+
+// Contains matches items whose field contains value.
+func Contains(field, value string) Filter {
+	return function{name: "contains", field: field, value: value}
+}
+
+// StartsWith matches items whose field starts with value.
+func StartsWith(field, value string) Filter {
+	return function{name: "startswith", field: field, value: value}
+}
+
+// And matches items matching every filter. Empty groups are dropped.
+func And(filters ...Filter) Filter {
+	return newGroup("and", filters)
+}
+
+// Or matches items matching any of the filters. Empty groups are dropped.
+func Or(filters ...Filter) Filter {
+	return newGroup("or", filters)
+}
+
+func newGroup(op string, filters []Filter) Filter {
+	g := group{op: op}
+	for _, f := range filters {
+		if f == nil || f.String() == "" {
+			continue
+		}
+		if nested, ok := f.(group); ok && nested.op == op {
+			g.filters = append(g.filters, nested.filters...)
+			continue
+		}
+		g.filters = append(g.filters, f)
+	}
+	if len(g.filters) == 1 {
+		return g.filters[0]
+	}
+	return g
+}
+
+// quote renders value as an OData string literal, doubling embedded quotes.
+func quote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// Query creates the filter for the search flags. Empty values are ignored.
+func Query(region string, service string, vmType string, pricingType string) Filter {
+	var filters []Filter
+	if region != "" {
+		filters = append(filters, Eq("armRegionName", region))
+	}
+	if service != "" {
+		filters = append(filters, Contains("serviceName", service))
+	}
+	if vmType != "" {
+		filters = append(filters, Contains("armSkuName", vmType))
+	}
+	if pricingType != "" {
+		filters = append(filters, Eq("priceType", pricingType))
+	}
+	return And(filters...)
+}
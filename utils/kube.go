@@ -0,0 +1,357 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CPUWeight is the share of a node's cost allocated by CPU requests, the
+// rest being allocated by memory requests.
+const CPUWeight = 0.5
+
+// KubeNode is a node of a cluster dump. CPU is in cores and Memory in bytes,
+// both allocatable.
+type KubeNode struct {
+	Name         string
+	InstanceType string
+	Region       string
+	Windows      bool
+	Spot         bool
+	CPU          float64
+	Memory       float64
+}
+
+// KubePod is a scheduled pod of a cluster dump with its effective requests.
+// Workload is the deployment, statefulset, daemonset or job owning the pod,
+// or the pod itself.
+type KubePod struct {
+	Namespace string
+	Name      string
+	Workload  string
+	Node      string
+	CPU       float64
+	Memory    float64
+}
+
+// KubeDump holds the nodes and pods read from 'kubectl get -o json' output.
+type KubeDump struct {
+	Nodes []KubeNode
+	Pods  []KubePod
+}
+
+// kubeObject is the part of a node, a pod or a list of them read from kubectl.
+type kubeObject struct {
+	Kind     string       `json:"kind"`
+	Items    []kubeObject `json:"items"`
+	Metadata struct {
+		Name            string            `json:"name"`
+		Namespace       string            `json:"namespace"`
+		Labels          map[string]string `json:"labels"`
+		OwnerReferences []struct {
+			Kind string `json:"kind"`
+			Name string `json:"name"`
+		} `json:"ownerReferences"`
+	} `json:"metadata"`
+	Spec struct {
+		NodeName       string          `json:"nodeName"`
+		Containers     []kubeContainer `json:"containers"`
+		InitContainers []kubeContainer `json:"initContainers"`
+		Overhead       kubeResources   `json:"overhead"`
+	} `json:"spec"`
+	Status struct {
+		Phase       string        `json:"phase"`
+		Allocatable kubeResources `json:"allocatable"`
+	} `json:"status"`
+}
+
+type kubeContainer struct {
+	Resources struct {
+		Requests kubeResources `json:"requests"`
+	} `json:"resources"`
+}
+
+type kubeResources struct {
+	CPU    string `json:"cpu"`
+	Memory string `json:"memory"`
+}
+
+// Decode adds the nodes and pods of the kubectl JSON output in r to
+// the dump. The output may hold several documents. Pods that are not
+// scheduled or have terminated are skipped.
+func (d *KubeDump) Decode(r io.Reader) error {
+	dec := json.NewDecoder(r)
+	for {
+		var object kubeObject
+		err := dec.Decode(&object)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading kubectl output: %w", err)
+		}
+		if err := d.add(object); err != nil {
+			return err
+		}
+	}
+}
+
+func (d *KubeDump) add(object kubeObject) error {
+	switch object.Kind {
+	case "List", "NodeList", "PodList":
+		for _, item := range object.Items {
+			if item.Kind == "" {
+				item.Kind = strings.TrimSuffix(object.Kind, "List")
+			}
+			if err := d.add(item); err != nil {
+				return err
+			}
+		}
+	case "Node":
+		node, err := kubeNode(object)
+		if err != nil {
+			return err
+		}
+		d.Nodes = append(d.Nodes, node)
+	case "Pod":
+		if object.Spec.NodeName == "" || object.Status.Phase == "Succeeded" || object.Status.Phase == "Failed" {
+			return nil
+		}
+		pod, err := kubePod(object)
+		if err != nil {
+			return err
+		}
+		d.Pods = append(d.Pods, pod)
+	}
+	return nil
+}
+
+func kubeNode(object kubeObject) (KubeNode, error) {
+	labels := object.Metadata.Labels
+	label := func(names ...string) string {
+		for _, name := range names {
+			if v := labels[name]; v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+	node := KubeNode{
+		Name:         object.Metadata.Name,
+		InstanceType: label("node.kubernetes.io/instance-type", "beta.kubernetes.io/instance-type"),
+		Region:       label("topology.kubernetes.io/region", "failure-domain.beta.kubernetes.io/region"),
+		Windows:      strings.EqualFold(label("kubernetes.io/os", "beta.kubernetes.io/os"), "windows"),
+		Spot:         strings.EqualFold(label("kubernetes.azure.com/scalesetpriority"), "spot"),
+	}
+	var err error
+	if node.CPU, err = ParseKubeQuantity(object.Status.Allocatable.CPU); err != nil {
+		return node, fmt.Errorf("node %s: allocatable cpu: %w", node.Name, err)
+	}
+	if node.Memory, err = ParseKubeQuantity(object.Status.Allocatable.Memory); err != nil {
+		return node, fmt.Errorf("node %s: allocatable memory: %w", node.Name, err)
+	}
+	return node, nil
+}
+
+// replicaSetHash is the pod template hash a deployment appends to the name
+// of its replica sets, written with an alphabet without vowels.
+var replicaSetHash = regexp.MustCompile(`-[bcdfghjklmnpqrstvwxz2456789]{6,10}$`)
+
+// jobTimestamp is the schedule time a cron job appends to the name of its jobs.
+var jobTimestamp = regexp.MustCompile(`-[0-9]{8,}$`)
+
+func kubePod(object kubeObject) (KubePod, error) {
+	pod := KubePod{
+		Namespace: object.Metadata.Namespace,
+		Name:      object.Metadata.Name,
+		Workload:  "pod/" + object.Metadata.Name,
+		Node:      object.Spec.NodeName,
+	}
+	if owners := object.Metadata.OwnerReferences; len(owners) > 0 {
+		kind, name := owners[0].Kind, owners[0].Name
+		switch kind {
+		case "ReplicaSet":
+			kind, name = "Deployment", replicaSetHash.ReplaceAllString(name, "")
+		case "Job":
+			if jobTimestamp.MatchString(name) {
+				kind, name = "CronJob", jobTimestamp.ReplaceAllString(name, "")
+			}
+		}
+		pod.Workload = strings.ToLower(kind) + "/" + name
+	}
+
+	// The effective request of a pod is the larger of its containers' sum
+	// and its largest init container, plus the pod overhead.
+	requests := func(resource func(kubeResources) string) (float64, error) {
+		var sum, init float64
+		for _, c := range object.Spec.Containers {
+			q, err := ParseKubeQuantity(resource(c.Resources.Requests))
+			if err != nil {
+				return 0, err
+			}
+			sum += q
+		}
+		for _, c := range object.Spec.InitContainers {
+			q, err := ParseKubeQuantity(resource(c.Resources.Requests))
+			if err != nil {
+				return 0, err
+			}
+			init = math.Max(init, q)
+		}
+		overhead, err := ParseKubeQuantity(resource(object.Spec.Overhead))
+		return math.Max(sum, init) + overhead, err
+	}
+	var err error
+	if pod.CPU, err = requests(func(r kubeResources) string { return r.CPU }); err != nil {
+		return pod, fmt.Errorf("pod %s/%s: cpu request: %w", pod.Namespace, pod.Name, err)
+	}
+	if pod.Memory, err = requests(func(r kubeResources) string { return r.Memory }); err != nil {
+		return pod, fmt.Errorf("pod %s/%s: memory request: %w", pod.Namespace, pod.Name, err)
+	}
+	return pod, nil
+}
+
+var kubeSuffixes = map[string]float64{
+	"n": 1e-9, "u": 1e-6, "m": 1e-3, "": 1,
+	"k": 1e3, "M": 1e6, "G": 1e9, "T": 1e12, "P": 1e15, "E": 1e18,
+	"Ki": 1 << 10, "Mi": 1 << 20, "Gi": 1 << 30, "Ti": 1 << 40, "Pi": 1 << 50, "Ei": 1 << 60,
+}
+
+var kubeQuantity = regexp.MustCompile(`^([+-]?[0-9.]+(?:[eE][+-]?[0-9]+)?)([a-zA-Z]*)$`)
+
+// ParseKubeQuantity reads a Kubernetes resource quantity such as "250m",
+// "1.5" or "512Mi". An empty quantity is 0.
+func ParseKubeQuantity(s string) (float64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	m := kubeQuantity.FindStringSubmatch(s)
+	if m == nil {
+		return 0, fmt.Errorf("invalid quantity %q", s)
+	}
+	multiplier, ok := kubeSuffixes[m[2]]
+	if !ok {
+		return 0, fmt.Errorf("invalid quantity %q", s)
+	}
+	number, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid quantity %q", s)
+	}
+	return number * multiplier, nil
+}
+
+// EstimateNodes prices every node as a VM of its instance type, once per
+// instance type, region, OS and priority. Nodes without a region label are
+// priced in the estimator's region. Lines are returned by node name.
+func (e *Estimator) EstimateNodes(ctx context.Context, nodes []KubeNode) (map[string]Line, error) {
+	priced := map[string]Line{}
+	lines := map[string]Line{}
+	for _, node := range nodes {
+		region := node.Region
+		if region == "" {
+			region = e.Region
+		}
+		key := strings.Join([]string{node.InstanceType, region, strconv.FormatBool(node.Windows), strconv.FormatBool(node.Spot)}, "|")
+		line, ok := priced[key]
+		if !ok {
+			r := VirtualMachine(node.Name, node.InstanceType, region, node.Windows, 1)
+			if node.Spot {
+				r.Meter = "Spot"
+			}
+			if node.InstanceType == "" {
+				line = Line{Resource: r, Err: fmt.Errorf("no node.kubernetes.io/instance-type label")}
+			} else {
+				var err error
+				if line, err = e.Price(ctx, r); err != nil {
+					return nil, err
+				}
+			}
+			priced[key] = line
+		}
+		line.Resource.Name = node.Name
+		lines[node.Name] = line
+	}
+	return lines, nil
+}
+
+// Allocation is the share of the cost of the nodes used by a workload of a
+// namespace, through its pods' requests.
+type Allocation struct {
+	Namespace string
+	Workload  string
+	Pods      int
+	CPU       float64
+	Memory    float64
+	Monthly   float64
+}
+
+// Allocate splits the monthly cost of every node between the pods it runs,
+// CPUWeight by their share of its allocatable CPU and the rest by their
+// share of its memory. What the requests leave is returned as idle, with the
+// unrequested CPU and memory. Pods on nodes missing from nodeCost are
+// counted but cost nothing. Allocations are sorted by namespace and workload.
+func (d *KubeDump) Allocate(nodeCost map[string]float64) ([]Allocation, Allocation) {
+	nodes := map[string]KubeNode{}
+	for _, node := range d.Nodes {
+		nodes[node.Name] = node
+	}
+	requested := map[string][2]float64{}
+	for _, pod := range d.Pods {
+		r := requested[pod.Node]
+		requested[pod.Node] = [2]float64{r[0] + pod.CPU, r[1] + pod.Memory}
+	}
+	// share returns the fraction of node a request uses. The requests of a
+	// node are scaled down when they exceed its capacity.
+	share := func(node KubeNode, cpu, memory float64) float64 {
+		r := requested[node.Name]
+		var s float64
+		if node.CPU > 0 {
+			s += CPUWeight * cpu / math.Max(node.CPU, r[0])
+		}
+		if node.Memory > 0 {
+			s += (1 - CPUWeight) * memory / math.Max(node.Memory, r[1])
+		}
+		return s
+	}
+
+	index := map[string]int{}
+	var allocations []Allocation
+	for _, pod := range d.Pods {
+		key := pod.Namespace + "/" + pod.Workload
+		i, ok := index[key]
+		if !ok {
+			i = len(allocations)
+			index[key] = i
+			allocations = append(allocations, Allocation{Namespace: pod.Namespace, Workload: pod.Workload})
+		}
+		a := &allocations[i]
+		a.Pods++
+		a.CPU += pod.CPU
+		a.Memory += pod.Memory
+		if node, ok := nodes[pod.Node]; ok {
+			a.Monthly += nodeCost[node.Name] * share(node, pod.CPU, pod.Memory)
+		}
+	}
+	sort.Slice(allocations, func(i, j int) bool {
+		if allocations[i].Namespace != allocations[j].Namespace {
+			return allocations[i].Namespace < allocations[j].Namespace
+		}
+		return allocations[i].Workload < allocations[j].Workload
+	})
+
+	var idle Allocation
+	for _, node := range d.Nodes {
+		r := requested[node.Name]
+		idle.CPU += math.Max(0, node.CPU-r[0])
+		idle.Memory += math.Max(0, node.Memory-r[1])
+		idle.Monthly += nodeCost[node.Name] * (1 - share(node, r[0], r[1]))
+	}
+	return allocations, idle
+}
@@ -0,0 +1,178 @@
+package utils
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestParseKubeQuantity(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+		err  bool
+	}{
+		{"", 0, false},
+		{"2", 2, false},
+		{"1.5", 1.5, false},
+		{"250m", 0.25, false},
+		{"512Mi", 512 << 20, false},
+		{"1Gi", 1 << 30, false},
+		{"2k", 2000, false},
+		{"1e3", 1000, false},
+		{"100M", 1e8, false},
+		{"cpu", 0, true},
+		{"1Xi", 0, true},
+		{"1.2.3", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseKubeQuantity(tt.in)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("ParseKubeQuantity(%q) = %g, %v, want %g, error %v", tt.in, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+// kubePodJSON is a scheduled pod owned by owner, of kind/name, with the given
+// container and init container CPU requests.
+func kubePodJSON(name, owner, containers, initContainers string) string {
+	owners := ""
+	if owner != "" {
+		kind, ownerName, _ := strings.Cut(owner, "/")
+		owners = `"ownerReferences": [{"kind": "` + kind + `", "name": "` + ownerName + `"}],`
+	}
+	return `{"kind": "Pod", "metadata": {"name": "` + name + `", "namespace": "default", ` + owners + ` "labels": {}},
+		"spec": {"nodeName": "node", "containers": [` + containers + `], "initContainers": [` + initContainers + `]},
+		"status": {"phase": "Running"}}`
+}
+
+func TestKubePodWorkload(t *testing.T) {
+	tests := []struct {
+		name  string
+		owner string
+		want  string
+	}{
+		{"web-7d9f8c6b5d-x2x7p", "ReplicaSet/web-7d9f8c6b5d", "deployment/web"},
+		{"api-v2-5c8b7dd4f-abcde", "ReplicaSet/api-v2-5c8b7dd4f", "deployment/api-v2"},
+		{"backup-28391040-kq2lz", "Job/backup-28391040", "cronjob/backup"},
+		{"migrate-9xk2p", "Job/migrate", "job/migrate"},
+		{"db-0", "StatefulSet/db", "statefulset/db"},
+		{"fluentd-8w2nz", "DaemonSet/fluentd", "daemonset/fluentd"},
+		{"debug", "", "pod/debug"},
+	}
+	for _, tt := range tests {
+		var d KubeDump
+		if err := d.Decode(strings.NewReader(kubePodJSON(tt.name, tt.owner, "", ""))); err != nil {
+			t.Fatal(err)
+		}
+		if len(d.Pods) != 1 || d.Pods[0].Workload != tt.want {
+			t.Errorf("pod %s owned by %s: got %+v, want workload %s", tt.name, tt.owner, d.Pods, tt.want)
+		}
+	}
+}
+
+func TestKubePodRequests(t *testing.T) {
+	request := func(cpu, memory string) string {
+		return `{"resources": {"requests": {"cpu": "` + cpu + `", "memory": "` + memory + `"}}}`
+	}
+	tests := []struct {
+		name           string
+		containers     string
+		initContainers string
+		cpu, memory    float64
+	}{
+		{"sum of containers", request("100m", "64Mi") + "," + request("200m", "64Mi"), "", 0.3, 128 << 20},
+		{"larger init container", request("100m", "64Mi") + "," + request("200m", "64Mi"), request("500m", "32Mi") + "," + request("50m", "256Mi"), 0.5, 256 << 20},
+		{"smaller init container", request("1", "1Gi"), request("500m", "512Mi"), 1, 1 << 30},
+		{"no requests", "{}", "", 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var d KubeDump
+			if err := d.Decode(strings.NewReader(kubePodJSON("p", "", tt.containers, tt.initContainers))); err != nil {
+				t.Fatal(err)
+			}
+			if pod := d.Pods[0]; math.Abs(pod.CPU-tt.cpu) > 1e-9 || pod.Memory != tt.memory {
+				t.Errorf("requests %g cpu %g memory, want %g and %g", pod.CPU, pod.Memory, tt.cpu, tt.memory)
+			}
+		})
+	}
+}
+
+func TestKubeDumpSkipsUnscheduledPods(t *testing.T) {
+	list := `{"kind": "List", "items": [
+		{"kind": "Node", "metadata": {"name": "node"}, "status": {"allocatable": {"cpu": "3860m", "memory": "12Gi"}}},
+		{"kind": "Pod", "metadata": {"name": "pending"}, "spec": {}, "status": {"phase": "Pending"}},
+		{"kind": "Pod", "metadata": {"name": "done"}, "spec": {"nodeName": "node"}, "status": {"phase": "Succeeded"}}
+	]}` + kubePodJSON("running", "", "", "")
+	var d KubeDump
+	if err := d.Decode(strings.NewReader(list)); err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Nodes) != 1 || d.Nodes[0].CPU != 3.86 || d.Nodes[0].Memory != 12<<30 {
+		t.Errorf("nodes %+v", d.Nodes)
+	}
+	if len(d.Pods) != 1 || d.Pods[0].Name != "running" {
+		t.Errorf("pods %+v, want only the running one", d.Pods)
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	const gi = 1 << 30
+	tests := []struct {
+		name    string
+		nodes   []KubeNode
+		pods    []KubePod
+		cost    map[string]float64
+		monthly map[string]float64
+		idle    Allocation
+	}{
+		{
+			name:  "idle is what requests leave",
+			nodes: []KubeNode{{Name: "a", CPU: 4, Memory: 8 * gi}},
+			pods: []KubePod{
+				{Namespace: "shop", Workload: "deployment/web", Node: "a", CPU: 1, Memory: 2 * gi},
+				{Namespace: "shop", Workload: "deployment/web", Node: "a", CPU: 1},
+			},
+			cost:    map[string]float64{"a": 100},
+			monthly: map[string]float64{"shop/deployment/web": 37.5},
+			idle:    Allocation{CPU: 2, Memory: 6 * gi, Monthly: 62.5},
+		},
+		{
+			name:  "overcommitted node",
+			nodes: []KubeNode{{Name: "b", CPU: 2, Memory: 4 * gi}},
+			pods: []KubePod{
+				{Namespace: "jobs", Workload: "job/etl", Node: "b", CPU: 2, Memory: gi},
+				{Namespace: "web", Workload: "deployment/api", Node: "b", CPU: 1, Memory: gi},
+			},
+			cost:    map[string]float64{"b": 60},
+			monthly: map[string]float64{"jobs/job/etl": 27.5, "web/deployment/api": 17.5},
+			idle:    Allocation{CPU: 0, Memory: 2 * gi, Monthly: 15},
+		},
+		{
+			name:    "pods on unpriced nodes",
+			nodes:   []KubeNode{{Name: "c", CPU: 2, Memory: 4 * gi}},
+			pods:    []KubePod{{Namespace: "shop", Workload: "deployment/web", Node: "c", CPU: 1, Memory: gi}},
+			cost:    map[string]float64{},
+			monthly: map[string]float64{"shop/deployment/web": 0},
+			idle:    Allocation{CPU: 1, Memory: 3 * gi},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &KubeDump{Nodes: tt.nodes, Pods: tt.pods}
+			allocations, idle := d.Allocate(tt.cost)
+			if len(allocations) != len(tt.monthly) {
+				t.Fatalf("allocations %+v, want %v", allocations, tt.monthly)
+			}
+			for _, a := range allocations {
+				if want := tt.monthly[a.Namespace+"/"+a.Workload]; math.Abs(a.Monthly-want) > 1e-9 {
+					t.Errorf("%s/%s monthly %g, want %g", a.Namespace, a.Workload, a.Monthly, want)
+				}
+			}
+			if math.Abs(idle.Monthly-tt.idle.Monthly) > 1e-9 || idle.CPU != tt.idle.CPU || idle.Memory != tt.idle.Memory {
+				t.Errorf("idle %+v, want %+v", idle, tt.idle)
+			}
+		})
+	}
+}
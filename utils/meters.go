@@ -0,0 +1,148 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The constructors below describe common Azure resources as estimate lines,
+// so that infrastructure code can be priced the same way as a spec file.
+
+// VirtualMachine prices count instances of a VM size. Linux is priced unless
+// windows is set.
+func VirtualMachine(name, size, region string, windows bool, count float64) Resource {
+	r := Resource{Name: name, Service: "Virtual Machines", SKU: size, Region: region, Quantity: &count}
+	if windows {
+		r.Product = "Windows"
+	} else {
+		r.ExcludeProduct = "Windows"
+	}
+	return r
+}
+
+// diskTiers are the managed disk sizes in GiB and the number of their tier.
+var diskTiers = []struct {
+	size int
+	tier int
+}{
+	{4, 1}, {8, 2}, {16, 3}, {32, 4}, {64, 6}, {128, 10}, {256, 15}, {512, 20},
+	{1024, 30}, {2048, 40}, {4096, 50}, {8192, 60}, {16384, 70}, {32767, 80},
+}
+
+// ManagedDisk prices a managed disk of storageType (e.g. "Premium_LRS") and
+// sizeGB, rounded up to its billed tier.
+func ManagedDisk(name, storageType string, sizeGB float64, region string) (Resource, error) {
+	kind, redundancy, _ := strings.Cut(storageType, "_")
+	var prefix string
+	switch strings.ToLower(kind) {
+	case "premium":
+		prefix = "P"
+	case "standardssd":
+		prefix = "E"
+	case "standard":
+		prefix = "S"
+	default:
+		return Resource{}, fmt.Errorf("disk type %q is not supported", storageType)
+	}
+	if redundancy == "" {
+		redundancy = "LRS"
+	}
+	tier := 0
+	for _, t := range diskTiers {
+		if float64(t.size) >= sizeGB {
+			tier = t.tier
+			break
+		}
+	}
+	if tier == 0 {
+		return Resource{}, fmt.Errorf("disk size %v GB is larger than the largest disk tier", sizeGB)
+	}
+	if prefix == "S" && tier < 4 {
+		// Standard HDD disks start at S4.
+		tier = 4
+	}
+	sku := fmt.Sprintf("%s%d %s", prefix, tier, strings.ToUpper(redundancy))
+	return Resource{Name: name, Service: "Storage", SKU: sku, Meter: sku + " Disk", Region: region}, nil
+}
+
+// PublicIP prices an IPv4 public IP address of sku ("Basic" or "Standard")
+// and allocation ("Static" or "Dynamic").
+func PublicIP(name, sku, allocation, region string) Resource {
+	if sku == "" {
+		sku = "Basic"
+	}
+	if allocation == "" || strings.EqualFold(sku, "Standard") {
+		allocation = "Static"
+	}
+	return Resource{
+		Name:    name,
+		Service: "Virtual Network",
+		Product: "IP Addresses",
+		Meter:   fmt.Sprintf("%s IPv4 %s", capitalize(sku), capitalize(allocation)),
+		Region:  region,
+	}
+}
+
+// Bandwidth prices gb of data transferred out to the internet over the
+// Microsoft network, the default routing preference, over the tiers of the
+// meter.
+func Bandwidth(name string, gb float64, region string) Resource {
+	return Resource{
+		Name:           name,
+		Service:        "Bandwidth",
+		Meter:          "Standard Data Transfer Out",
+		ExcludeProduct: "Routing Preference: Internet",
+		Region:         region,
+		Usage:          Usage{GB: gb},
+	}
+}
+
+// BlobStorage prices gb of block blob capacity in an access tier ("Hot",
+// "Cool"...) with a replication such as "LRS" or "RA-GRS".
+func BlobStorage(name, accessTier, replication string, gb float64, region string) Resource {
+	if accessTier == "" {
+		accessTier = "Hot"
+	}
+	if replication == "" {
+		replication = "LRS"
+	}
+	sku := capitalize(accessTier) + " " + strings.ToUpper(replication)
+	return Resource{
+		Name:    name,
+		Service: "Storage",
+		Product: "General Block Blob v2",
+		SKU:     sku,
+		Meter:   "Data Stored",
+		Region:  region,
+		Usage:   Usage{GB: gb},
+	}
+}
+
+// AppServicePlan prices count instances of an App Service plan sku such as
+// "P1v3" or "S1".
+func AppServicePlan(name, sku string, linux bool, region string, count float64) Resource {
+	// The API spells the tier generation apart: "P1v3" is "P1 v3".
+	if i := strings.LastIndex(strings.ToLower(sku), "v"); i > 0 {
+		sku = sku[:i] + " " + strings.ToLower(sku[i:])
+	}
+	r := Resource{Name: name, Service: "Azure App Service", SKU: sku, Region: region, Quantity: &count}
+	if linux {
+		r.Product = "Linux"
+	} else {
+		r.ExcludeProduct = "Linux"
+	}
+	return r
+}
+
+// NormalizeRegion turns a display location such as "West Europe" into its
+// armRegionName, "westeurope".
+func NormalizeRegion(location string) string {
+	return strings.ToLower(strings.ReplaceAll(location, " ", ""))
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + strings.ToLower(s[1:])
+}
@@ -1,52 +1,44 @@
 package utils
 
 import (
-	"encoding/json"
-	"fmt"
-	"io"
-	"net/http"
+	"time"
 )
 
+// Item is a single price returned by the Azure Retail Prices API.
 type Item struct {
-	ArmRegionName string  `json:"armRegionName"`
-	ArmSkuName    string  `json:"armSkuName"`
-	MeterName     string  `json:"meterName"`
-	ProductName   string  `json:"productName"`
-	RetailPrice   float64 `json:"retailPrice"`
-	UnitOfMeasure string  `json:"unitOfMeasure"`
+	CurrencyCode         string            `json:"currencyCode"`
+	TierMinimumUnits     float64           `json:"tierMinimumUnits"`
+	ReservationTerm      string            `json:"reservationTerm,omitempty"`
+	RetailPrice          float64           `json:"retailPrice"`
+	UnitPrice            float64           `json:"unitPrice"`
+	ArmRegionName        string            `json:"armRegionName"`
+	Location             string            `json:"location"`
+	EffectiveStartDate   time.Time         `json:"effectiveStartDate"`
+	EffectiveEndDate     *time.Time        `json:"effectiveEndDate,omitempty"`
+	MeterID              string            `json:"meterId"`
+	MeterName            string            `json:"meterName"`
+	ProductID            string            `json:"productId"`
+	SkuID                string            `json:"skuId"`
+	ProductName          string            `json:"productName"`
+	SkuName              string            `json:"skuName"`
+	ServiceName          string            `json:"serviceName"`
+	ServiceID            string            `json:"serviceId"`
+	ServiceFamily        string            `json:"serviceFamily"`
+	UnitOfMeasure        string            `json:"unitOfMeasure"`
+	Type                 string            `json:"type"`
+	IsPrimaryMeterRegion bool              `json:"isPrimaryMeterRegion"`
+	ArmSkuName           string            `json:"armSkuName"`
+	SavingsPlan          []SavingsPlanTerm `json:"savingsPlan,omitempty"`
+}
+
+// SavingsPlanTerm is the savings plan price of a consumption meter for one term.
+type SavingsPlanTerm struct {
+	UnitPrice   float64 `json:"unitPrice" yaml:"unitPrice"`
+	RetailPrice float64 `json:"retailPrice" yaml:"retailPrice"`
+	Term        string  `json:"term" yaml:"term"`
 }
 
 type Response struct {
 	Items        []Item `json:"Items"`
 	NextPageLink string `json:"NextPageLink"`
 }
-
-// GetJSON fetches JSON data from a URL and decodes it into the provided interface
-func GetJSON(url string, v interface{}) error {
-	resp, err := http.Get(url)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("request failed with status %s: %s", resp.Status, string(bodyBytes))
-	}
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return err
-	}
-
-	return json.Unmarshal(body, v)
-}
-
-// Query creates the query string for the Azure pricing API
-func Query(region string, service string, vmType string, pricingType string) string {
-	var query string
-	if service != "" {
-		query = fmt.Sprintf("armRegionName eq '%s' and contains(serviceName, '%s')", region, service)
-	} else if vmType != "" {
-		query = fmt.Sprintf("armRegionName eq '%s' and contains(armSkuName, '%s') and priceType eq '%s'", region, vmType, pricingType)
-	}
-	return query
-}
@@ -0,0 +1,104 @@
+package utils
+
+import "strings"
+
+// Region describes an Azure region: its armRegionName, display name, the
+// geography holding its data, the larger group of geographies it belongs to
+// and its paired region, if any.
+type Region struct {
+	Name           string
+	DisplayName    string
+	Geography      string
+	GeographyGroup string
+	Paired         string
+}
+
+// Regions are the public Azure regions.
+var Regions = []Region{
+	{"eastus", "East US", "United States", "US", "westus"},
+	{"eastus2", "East US 2", "United States", "US", "centralus"},
+	{"centralus", "Central US", "United States", "US", "eastus2"},
+	{"northcentralus", "North Central US", "United States", "US", "southcentralus"},
+	{"southcentralus", "South Central US", "United States", "US", "northcentralus"},
+	{"westcentralus", "West Central US", "United States", "US", "westus2"},
+	{"westus", "West US", "United States", "US", "eastus"},
+	{"westus2", "West US 2", "United States", "US", "westcentralus"},
+	{"westus3", "West US 3", "United States", "US", "eastus"},
+	{"canadacentral", "Canada Central", "Canada", "Canada", "canadaeast"},
+	{"canadaeast", "Canada East", "Canada", "Canada", "canadacentral"},
+	{"mexicocentral", "Mexico Central", "Mexico", "Mexico", ""},
+	{"brazilsouth", "Brazil South", "Brazil", "South America", "southcentralus"},
+	{"brazilsoutheast", "Brazil Southeast", "Brazil", "South America", "brazilsouth"},
+	{"northeurope", "North Europe", "Europe", "Europe", "westeurope"},
+	{"westeurope", "West Europe", "Europe", "Europe", "northeurope"},
+	{"francecentral", "France Central", "France", "Europe", "francesouth"},
+	{"francesouth", "France South", "France", "Europe", "francecentral"},
+	{"germanywestcentral", "Germany West Central", "Germany", "Europe", "germanynorth"},
+	{"germanynorth", "Germany North", "Germany", "Europe", "germanywestcentral"},
+	{"italynorth", "Italy North", "Italy", "Europe", ""},
+	{"norwayeast", "Norway East", "Norway", "Europe", "norwaywest"},
+	{"norwaywest", "Norway West", "Norway", "Europe", "norwayeast"},
+	{"polandcentral", "Poland Central", "Poland", "Europe", ""},
+	{"spaincentral", "Spain Central", "Spain", "Europe", ""},
+	{"swedencentral", "Sweden Central", "Sweden", "Europe", "swedensouth"},
+	{"swedensouth", "Sweden South", "Sweden", "Europe", "swedencentral"},
+	{"switzerlandnorth", "Switzerland North", "Switzerland", "Europe", "switzerlandwest"},
+	{"switzerlandwest", "Switzerland West", "Switzerland", "Europe", "switzerlandnorth"},
+	{"uksouth", "UK South", "United Kingdom", "Europe", "ukwest"},
+	{"ukwest", "UK West", "United Kingdom", "Europe", "uksouth"},
+	{"israelcentral", "Israel Central", "Israel", "Middle East", ""},
+	{"qatarcentral", "Qatar Central", "Qatar", "Middle East", ""},
+	{"uaenorth", "UAE North", "UAE", "Middle East", "uaecentral"},
+	{"uaecentral", "UAE Central", "UAE", "Middle East", "uaenorth"},
+	{"southafricanorth", "South Africa North", "South Africa", "Africa", "southafricawest"},
+	{"southafricawest", "South Africa West", "South Africa", "Africa", "southafricanorth"},
+	{"centralindia", "Central India", "India", "Asia Pacific", "southindia"},
+	{"southindia", "South India", "India", "Asia Pacific", "centralindia"},
+	{"westindia", "West India", "India", "Asia Pacific", "southindia"},
+	{"jioindiacentral", "Jio India Central", "India", "Asia Pacific", "jioindiawest"},
+	{"jioindiawest", "Jio India West", "India", "Asia Pacific", "jioindiacentral"},
+	{"eastasia", "East Asia", "Asia Pacific", "Asia Pacific", "southeastasia"},
+	{"southeastasia", "Southeast Asia", "Asia Pacific", "Asia Pacific", "eastasia"},
+	{"japaneast", "Japan East", "Japan", "Asia Pacific", "japanwest"},
+	{"japanwest", "Japan West", "Japan", "Asia Pacific", "japaneast"},
+	{"koreacentral", "Korea Central", "Korea", "Asia Pacific", "koreasouth"},
+	{"koreasouth", "Korea South", "Korea", "Asia Pacific", "koreacentral"},
+	{"australiaeast", "Australia East", "Australia", "Asia Pacific", "australiasoutheast"},
+	{"australiasoutheast", "Australia Southeast", "Australia", "Asia Pacific", "australiaeast"},
+	{"australiacentral", "Australia Central", "Australia", "Asia Pacific", "australiacentral2"},
+	{"australiacentral2", "Australia Central 2", "Australia", "Asia Pacific", "australiacentral"},
+}
+
+// LookupRegion returns the metadata of a region given by armRegionName or
+// display name.
+func LookupRegion(name string) (Region, bool) {
+	name = NormalizeRegion(name)
+	for _, r := range Regions {
+		if r.Name == name {
+			return r, true
+		}
+	}
+	return Region{}, false
+}
+
+// InGeography reports whether the region is in geo, a geography such as
+// "Germany" or a group such as "Europe", matched ignoring case and spaces.
+func (r Region) InGeography(geo string) bool {
+	geo = NormalizeRegion(geo)
+	return geo != "" && (geo == NormalizeRegion(r.Geography) || geo == NormalizeRegion(r.GeographyGroup))
+}
+
+// Geographies lists the geographies and groups InGeography accepts.
+func Geographies() []string {
+	var geos []string
+	seen := map[string]bool{}
+	for _, r := range Regions {
+		for _, geo := range []string{r.GeographyGroup, r.Geography} {
+			if !seen[strings.ToLower(geo)] {
+				seen[strings.ToLower(geo)] = true
+				geos = append(geos, geo)
+			}
+		}
+	}
+	return geos
+}
@@ -0,0 +1,91 @@
+package utils
+
+import "sort"
+
+// The ways to commit to a VM compared to paying as you go.
+const (
+	SavingsPlan = "Savings Plan"
+	Reservation = "Reservation"
+)
+
+// Commitment is a way to pay for a VM, pay as you go, a savings plan or a
+// reservation, with its effective monthly cost.
+type Commitment struct {
+	Kind    string
+	Term    string
+	Monthly float64
+	// Total is the cost over the whole term, that of a month when paying
+	// as you go.
+	Total float64
+}
+
+// Savings returns the percentage saved on the pay as you go monthly cost.
+func (c Commitment) Savings(payg float64) float64 {
+	if payg == 0 {
+		return 0
+	}
+	return (1 - c.Monthly/payg) * 100
+}
+
+// BreakEven returns the utilization, the percentage of the month the VM runs,
+// below which the commitment costs more than paying as you go for the hours
+// used. It is over 100 when the commitment never pays off.
+func (c Commitment) BreakEven(payg float64) float64 {
+	if payg == 0 {
+		return 0
+	}
+	return c.Monthly / payg * 100
+}
+
+// Commitments returns the pay as you go price of a VM followed by its savings
+// plans and reservations, shortest term first, from its price items. Items
+// of several VMs or operating systems should not be mixed. It reports false
+// when there is no pay as you go price.
+func Commitments(items []Item) ([]Commitment, bool) {
+	var payg *Item
+	reserved := map[string]Item{}
+	for i, item := range items {
+		if item.TierMinimumUnits > 0 {
+			continue
+		}
+		switch PriceTypeOf(item) {
+		case PayAsYouGo:
+			if payg == nil || item.RetailPrice < payg.RetailPrice {
+				payg = &items[i]
+			}
+		case Reserved1Y, Reserved3Y:
+			if current, ok := reserved[item.ReservationTerm]; !ok || item.RetailPrice < current.RetailPrice {
+				reserved[item.ReservationTerm] = item
+			}
+		}
+	}
+	if payg == nil {
+		return nil, false
+	}
+	monthly, ok := MonthlyCost(*payg)
+	if !ok {
+		return nil, false
+	}
+	commitments := []Commitment{{Kind: PayAsYouGo, Monthly: monthly, Total: monthly}}
+
+	var terms []Commitment
+	unit, _ := ParseUnit(payg.UnitOfMeasure)
+	for _, plan := range payg.SavingsPlan {
+		months := TermMonths(plan.Term)
+		if monthly, ok := MonthlyPrice(unit, plan.RetailPrice, 0); ok && months > 0 {
+			terms = append(terms, Commitment{Kind: SavingsPlan, Term: plan.Term, Monthly: monthly, Total: monthly * float64(months)})
+		}
+	}
+	for term, item := range reserved {
+		if monthly, ok := MonthlyCost(item); ok {
+			terms = append(terms, Commitment{Kind: Reservation, Term: term, Monthly: monthly, Total: item.RetailPrice})
+		}
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		if mi, mj := TermMonths(terms[i].Term), TermMonths(terms[j].Term); mi != mj {
+			return mi < mj
+		}
+		return terms[i].Kind < terms[j].Kind
+	})
+	return append(commitments, terms...), true
+}
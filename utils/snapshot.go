@@ -0,0 +1,288 @@
+package utils
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Source lists the price items matching a filter. Both the API client and a
+// snapshot file are sources.
+type Source interface {
+	Each(ctx context.Context, filter Filter, fn func(Item) error) error
+}
+
+// ListItems collects every item of src matching filter.
+func ListItems(ctx context.Context, src Source, filter Filter) ([]Item, error) {
+	var items []Item
+	err := src.Each(ctx, filter, func(item Item) error {
+		items = append(items, item)
+		return nil
+	})
+	return items, err
+}
+
+// SnapshotVersion is the version of the snapshot file format.
+const SnapshotVersion = 1
+
+// SnapshotHeader is the first line of a snapshot file, the other lines are items.
+type SnapshotHeader struct {
+	Version    int       `json:"version"`
+	Currency   string    `json:"currency"`
+	APIVersion string    `json:"apiVersion"`
+	Filter     string    `json:"filter,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
+	Items      int       `json:"items"`
+}
+
+// ServiceFamilies are the known serviceFamily values, used to split a
+// snapshot pull into queries run concurrently.
+var ServiceFamilies = []string{
+	"AI + Machine Learning", "Analytics", "Azure Arc", "Azure Communication Services", "Azure Security",
+	"Azure Stack", "Compute", "Containers", "Data", "Databases", "Developer Tools", "Dynamics", "Gaming",
+	"Integration", "Internet of Things", "Management and Governance", "Microsoft Syntex", "Mixed Reality",
+	"Networking", "Other", "Power Platform", "Quantum Computing", "Security", "Storage", "Telecommunications",
+	"Web", "Windows Virtual Desktop",
+}
+
+// PullOptions configure a snapshot pull.
+type PullOptions struct {
+	// Filter restricts the snapshot to a subset of the catalog, nil pulls everything.
+	Filter Filter
+	// Concurrency is the number of queries run at the same time.
+	Concurrency int
+	// Progress, when set, is called as each part of the catalog completes.
+	Progress func(part string, items int)
+}
+
+// PullSnapshot downloads the catalog with c and writes it to path as a gzipped
+// JSON lines snapshot. The file is only written once every query succeeded.
+func PullSnapshot(ctx context.Context, c *Client, path string, opts PullOptions) (*SnapshotHeader, error) {
+	tmpDir, err := os.MkdirTemp("", "cloudcost-snapshot-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// One query per service family, and one for the families not listed.
+	type part struct {
+		name   string
+		filter Filter
+		file   string
+		items  int
+	}
+	parts := make([]*part, 0, len(ServiceFamilies)+1)
+	others := []Filter{opts.Filter}
+	for i, family := range ServiceFamilies {
+		parts = append(parts, &part{name: family, filter: And(opts.Filter, Eq("serviceFamily", family)), file: filepath.Join(tmpDir, fmt.Sprint(i))})
+		others = append(others, Ne("serviceFamily", family))
+	}
+	parts = append(parts, &part{name: "(other families)", filter: And(others...), file: filepath.Join(tmpDir, "other")})
+
+	concurrency := opts.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+		queue    = make(chan *part)
+	)
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for p := range queue {
+				err := pullPart(ctx, c, p.filter, p.file, &p.items)
+				mu.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = fmt.Errorf("%s: %w", p.name, err)
+					cancel()
+				}
+				if err == nil && opts.Progress != nil {
+					opts.Progress(p.name, p.items)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, p := range parts {
+		select {
+		case queue <- p:
+		case <-ctx.Done():
+		}
+	}
+	close(queue)
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	header := &SnapshotHeader{Version: SnapshotVersion, Currency: c.Currency, APIVersion: c.APIVersion, CreatedAt: time.Now().UTC()}
+	if opts.Filter != nil {
+		header.Filter = opts.Filter.String()
+	}
+	for _, p := range parts {
+		header.Items += p.items
+	}
+	if header.Currency == "" {
+		header.Currency = "USD"
+	}
+
+	// Write next to the destination and rename, so that an interrupted pull
+	// never leaves a truncated snapshot behind.
+	out, err := os.CreateTemp(filepath.Dir(path), ".snapshot-*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(out.Name())
+	zw := gzip.NewWriter(out)
+	err = json.NewEncoder(zw).Encode(header)
+	for _, p := range parts {
+		if err != nil {
+			break
+		}
+		err = appendFile(zw, p.file)
+	}
+	if err == nil {
+		err = zw.Close()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return header, os.Rename(out.Name(), path)
+}
+
+// pullPart writes the items matching filter to file as JSON lines.
+func pullPart(ctx context.Context, c *Client, filter Filter, file string, count *int) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	err = c.Each(ctx, filter, func(item Item) error {
+		*count++
+		return enc.Encode(item)
+	})
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func appendFile(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// Snapshot is a price catalog read from a snapshot file. Every query streams
+// the file, so that a regional catalog is never held in memory.
+type Snapshot struct {
+	Path   string
+	Header SnapshotHeader
+}
+
+// OpenSnapshot reads the header of a snapshot file.
+func OpenSnapshot(path string) (*Snapshot, error) {
+	f, zr, dec, err := openSnapshot(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	defer zr.Close()
+	s := &Snapshot{Path: path}
+	if err := dec.Decode(&s.Header); err != nil {
+		return nil, fmt.Errorf("%s is not a snapshot: %w", path, err)
+	}
+	if s.Header.Version != SnapshotVersion {
+		return nil, fmt.Errorf("%s: unsupported snapshot version %d", path, s.Header.Version)
+	}
+	return s, nil
+}
+
+func openSnapshot(path string) (*os.File, *gzip.Reader, *json.Decoder, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	zr, err := gzip.NewReader(bufio.NewReader(f))
+	if err != nil {
+		f.Close()
+		return nil, nil, nil, fmt.Errorf("%s is not a snapshot: %w", path, err)
+	}
+	return f, zr, json.NewDecoder(zr), nil
+}
+
+// Each calls fn for every item of the snapshot matching filter, decoding the
+// file line by line.
+func (s *Snapshot) Each(ctx context.Context, filter Filter, fn func(Item) error) error {
+	return s.Scan(func(item Item) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if filter != nil && !filter.Match(item) {
+			return nil
+		}
+		return fn(item)
+	})
+}
+
+// Scan streams every item of the snapshot file to fn without keeping them in
+// memory.
+func (s *Snapshot) Scan(fn func(Item) error) error {
+	f, zr, dec, err := openSnapshot(s.Path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	defer zr.Close()
+	var header SnapshotHeader
+	if err := dec.Decode(&header); err != nil {
+		return err
+	}
+	count := 0
+	for {
+		var item Item
+		err := dec.Decode(&item)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", s.Path, err)
+		}
+		if err := fn(item); err != nil {
+			return err
+		}
+		count++
+	}
+	if count != header.Items {
+		return fmt.Errorf("%s is truncated: %d of %d items", s.Path, count, header.Items)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// BlobMinimumDays are the days blobs are billed for at least in the access
+// tiers that charge early deletion.
+var BlobMinimumDays = map[string]int{"cool": 30, "cold": 90, "archive": 180}
+
+// BlobRedundancies lists the replications of block blob storage.
+var BlobRedundancies = []string{"LRS", "ZRS", "GRS", "RA-GRS", "GZRS", "RA-GZRS"}
+
+// StorageAccount describes the block blobs of a storage account to price.
+type StorageAccount struct {
+	Currency string `yaml:"currency"`
+	Region   string `yaml:"region"`
+	// Redundancy is one of BlobRedundancies, LRS when empty.
+	Redundancy string        `yaml:"redundancy"`
+	Tiers      []StorageTier `yaml:"tiers"`
+}
+
+// StorageTier is the monthly usage of an access tier: hot, cool, cold or
+// archive. Operations are counts, EarlyDeleteGB are deleted or moved out of
+// the tier after EarlyDeleteDays.
+type StorageTier struct {
+	Tier            string  `yaml:"tier"`
+	GB              float64 `yaml:"gb"`
+	ReadOperations  float64 `yaml:"readOperations"`
+	WriteOperations float64 `yaml:"writeOperations"`
+	ListOperations  float64 `yaml:"listOperations"`
+	RetrievalGB     float64 `yaml:"retrievalGB"`
+	EarlyDeleteGB   float64 `yaml:"earlyDeleteGB"`
+	EarlyDeleteDays float64 `yaml:"earlyDeleteDays"`
+}
+
+// LoadStorageAccount reads a YAML or JSON storage account file.
+func LoadStorageAccount(path string) (*StorageAccount, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var a StorageAccount
+	if err := yaml.Unmarshal(data, &a); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &a, nil
+}
+
+// ParseStorageTier reads an access tier from comma separated key=value
+// pairs, e.g. "tier=cool,gb=5000,readOperations=1e6". Keys are the YAML
+// field names, matched ignoring case.
+func ParseStorageTier(s string) (StorageTier, error) {
+	var t StorageTier
+	err := t.Apply(s)
+	return t, err
+}
+
+// Apply sets the fields named by comma separated key=value pairs, as read by
+// ParseStorageTier, and leaves the others unchanged.
+func (t *StorageTier) Apply(s string) error {
+	for _, pair := range strings.Split(s, ",") {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return fmt.Errorf("tier %q: expected key=value, got %q", s, pair)
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if strings.EqualFold(key, "tier") {
+			t.Tier = value
+			continue
+		}
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("tier %q: %s: %w", s, key, err)
+		}
+		switch strings.ToLower(key) {
+		case "gb":
+			t.GB = number
+		case "readoperations", "reads":
+			t.ReadOperations = number
+		case "writeoperations", "writes":
+			t.WriteOperations = number
+		case "listoperations", "lists":
+			t.ListOperations = number
+		case "retrievalgb":
+			t.RetrievalGB = number
+		case "earlydeletegb":
+			t.EarlyDeleteGB = number
+		case "earlydeletedays":
+			t.EarlyDeleteDays = number
+		default:
+			return fmt.Errorf("tier %q: unknown key %q", s, key)
+		}
+	}
+	return nil
+}
+
+// Resources lists the meters billing the account: the capacity, the read,
+// write and list operations, the data retrieval and the early deletion of
+// every tier. Early deletion is billed as the capacity of the deleted data
+// for the rest of the tier's minimum days.
+func (a *StorageAccount) Resources() ([]Resource, error) {
+	if len(a.Tiers) == 0 {
+		return nil, fmt.Errorf("the storage account has no access tier")
+	}
+	redundancy := strings.ToUpper(a.Redundancy)
+	if redundancy == "" {
+		redundancy = "LRS"
+	}
+	known := false
+	for _, r := range BlobRedundancies {
+		known = known || r == redundancy
+	}
+	if !known {
+		return nil, fmt.Errorf("unknown redundancy %q (available: %s)", a.Redundancy, strings.Join(BlobRedundancies, ", "))
+	}
+
+	var resources []Resource
+	for _, t := range a.Tiers {
+		tier := strings.ToLower(t.Tier)
+		minDays, charged := BlobMinimumDays[tier]
+		if tier != "hot" && !charged {
+			return nil, fmt.Errorf("unknown access tier %q, expected hot, cool, cold or archive", t.Tier)
+		}
+		if tier == "archive" && strings.Contains(redundancy, "ZRS") {
+			return nil, fmt.Errorf("the archive tier does not support %s", redundancy)
+		}
+		if !charged && (t.RetrievalGB > 0 || t.EarlyDeleteGB > 0) {
+			return nil, fmt.Errorf("the %s tier has no data retrieval or early deletion charge", tier)
+		}
+		meter := func(name, meter string, usage Usage) Resource {
+			r := BlobStorage(tier+"/"+name, tier, redundancy, 0, a.Region)
+			r.Meter, r.Usage = meter, usage
+			return r
+		}
+		if t.GB > 0 {
+			resources = append(resources, meter("capacity", "Data Stored", Usage{GB: t.GB}))
+		}
+		if t.WriteOperations > 0 {
+			resources = append(resources, meter("write-operations", "Write Operations", Usage{Transactions: t.WriteOperations}))
+		}
+		if t.ReadOperations > 0 {
+			// Archive also has Priority reads and retrievals, named after the tier too.
+			resources = append(resources, meter("read-operations", capitalize(tier)+" Read Operations", Usage{Transactions: t.ReadOperations}))
+		}
+		if t.ListOperations > 0 {
+			resources = append(resources, meter("list-operations", "List and Create Container Operations", Usage{Transactions: t.ListOperations}))
+		}
+		if t.RetrievalGB > 0 {
+			resources = append(resources, meter("retrieval", capitalize(tier)+" Data Retrieval", Usage{GB: t.RetrievalGB}))
+		}
+		if remaining := float64(minDays) - t.EarlyDeleteDays; t.EarlyDeleteGB > 0 && remaining > 0 {
+			hours := remaining * 24
+			resources = append(resources, meter("early-deletion", "Data Stored", Usage{GB: t.EarlyDeleteGB, Hours: &hours}))
+		}
+	}
+	return resources, nil
+}
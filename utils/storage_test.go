@@ -0,0 +1,107 @@
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseStorageTier(t *testing.T) {
+	got, err := ParseStorageTier(" tier = Cool , gb=5000, reads = 1e6,retrievalGB=100 ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := StorageTier{Tier: "Cool", GB: 5000, ReadOperations: 1e6, RetrievalGB: 100}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	for _, s := range []string{"tier=hot,gb", "gb=lots", "tier=hot,deletes=5"} {
+		if _, err := ParseStorageTier(s); err == nil {
+			t.Errorf("%q was accepted", s)
+		}
+	}
+
+	// Applied to a tier, only the fields set change.
+	tier := StorageTier{Tier: "hot", GB: 1000, WriteOperations: 5e6}
+	if err := tier.Apply("tier=hot,gb=2000"); err != nil {
+		t.Fatal(err)
+	}
+	if want := (StorageTier{Tier: "hot", GB: 2000, WriteOperations: 5e6}); tier != want {
+		t.Errorf("applied %+v, want %+v", tier, want)
+	}
+}
+
+func TestStorageAccountResources(t *testing.T) {
+	hours := func(days float64) *float64 { h := days * 24; return &h }
+	type meter struct {
+		name, sku, meter string
+		usage            Usage
+	}
+	tests := []struct {
+		name    string
+		account StorageAccount
+		want    []meter
+		err     string
+	}{
+		{
+			name: "hot tier operations",
+			account: StorageAccount{Tiers: []StorageTier{
+				{Tier: "Hot", GB: 2000, WriteOperations: 5e6, ReadOperations: 4e7, ListOperations: 1e5},
+			}},
+			want: []meter{
+				{"hot/capacity", "Hot LRS", "Data Stored", Usage{GB: 2000}},
+				{"hot/write-operations", "Hot LRS", "Write Operations", Usage{Transactions: 5e6}},
+				{"hot/read-operations", "Hot LRS", "Hot Read Operations", Usage{Transactions: 4e7}},
+				{"hot/list-operations", "Hot LRS", "List and Create Container Operations", Usage{Transactions: 1e5}},
+			},
+		},
+		{
+			name: "retrieval and early deletion",
+			account: StorageAccount{Redundancy: "ra-grs", Tiers: []StorageTier{
+				{Tier: "archive", GB: 1e5, RetrievalGB: 500, EarlyDeleteGB: 1000, EarlyDeleteDays: 60},
+			}},
+			want: []meter{
+				{"archive/capacity", "Archive RA-GRS", "Data Stored", Usage{GB: 1e5}},
+				{"archive/retrieval", "Archive RA-GRS", "Archive Data Retrieval", Usage{GB: 500}},
+				{"archive/early-deletion", "Archive RA-GRS", "Data Stored", Usage{GB: 1000, Hours: hours(120)}},
+			},
+		},
+		{
+			name: "deleted after the minimum days",
+			account: StorageAccount{Tiers: []StorageTier{
+				{Tier: "cool", GB: 100, EarlyDeleteGB: 50, EarlyDeleteDays: 30},
+			}},
+			want: []meter{{"cool/capacity", "Cool LRS", "Data Stored", Usage{GB: 100}}},
+		},
+		{name: "no tier", account: StorageAccount{}, err: "no access tier"},
+		{name: "unknown tier", account: StorageAccount{Tiers: []StorageTier{{Tier: "warm", GB: 1}}}, err: `unknown access tier "warm"`},
+		{name: "unknown redundancy", account: StorageAccount{Redundancy: "XRS", Tiers: []StorageTier{{Tier: "hot", GB: 1}}}, err: `unknown redundancy "XRS"`},
+		{name: "archive with ZRS", account: StorageAccount{Redundancy: "ZRS", Tiers: []StorageTier{{Tier: "archive", GB: 1}}}, err: "does not support ZRS"},
+		{name: "hot retrieval", account: StorageAccount{Tiers: []StorageTier{{Tier: "hot", RetrievalGB: 1}}}, err: "no data retrieval"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.account.Region = "westeurope"
+			resources, err := tt.account.Resources()
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("got %v, want an error containing %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []meter
+			for _, r := range resources {
+				if r.Service != "Storage" || r.Region != "westeurope" {
+					t.Errorf("%s: service %q region %q", r.Name, r.Service, r.Region)
+				}
+				got = append(got, meter{r.Name, r.SKU, r.Meter, r.Usage})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("resources %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
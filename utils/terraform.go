@@ -0,0 +1,266 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Plan is the subset of `terraform show -json` output used to price a plan.
+type Plan struct {
+	ResourceChanges []ResourceChange `json:"resource_changes"`
+}
+
+// ResourceChange is a planned change of one resource instance.
+type ResourceChange struct {
+	Address string `json:"address"`
+	Mode    string `json:"mode"`
+	Type    string `json:"type"`
+	Change  struct {
+		Actions []string       `json:"actions"`
+		Before  map[string]any `json:"before"`
+		After   map[string]any `json:"after"`
+	} `json:"change"`
+}
+
+// LoadPlan reads a JSON plan produced by `terraform show -json`.
+func LoadPlan(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var plan Plan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, fmt.Errorf("%s is not a terraform JSON plan: %w", path, err)
+	}
+	return &plan, nil
+}
+
+// TerraformOptions hold the assumptions used for values a plan does not carry.
+type TerraformOptions struct {
+	// Region is used for resources without a location, such as node pools.
+	Region string
+	// StorageGB is the capacity assumed for storage accounts.
+	StorageGB float64
+}
+
+// PlanCost is the monthly cost of a resource before and after a plan.
+type PlanCost struct {
+	Change        ResourceChange
+	Before        []Line
+	After         []Line
+	BeforeMonthly float64
+	AfterMonthly  float64
+	// Err is set when the resource could not be priced.
+	Err error
+}
+
+// Action summarizes the planned actions, e.g. "create" or "delete, create".
+func (c PlanCost) Action() string {
+	return strings.Join(c.Change.Change.Actions, ", ")
+}
+
+// EstimatePlan prices the managed azurerm resources of plan before and after
+// the change. Unsupported resources are returned with Err set.
+func (e *Estimator) EstimatePlan(ctx context.Context, plan *Plan, opts TerraformOptions) ([]PlanCost, error) {
+	var costs []PlanCost
+	for _, change := range plan.ResourceChanges {
+		if change.Mode != "managed" || !strings.HasPrefix(change.Type, "azurerm_") {
+			continue
+		}
+		cost := PlanCost{Change: change}
+		var err error
+		beforeOpts, afterOpts := opts, opts
+		if change.Type == "azurerm_kubernetes_cluster_node_pool" {
+			beforeOpts, afterOpts = nodePoolOptions(plan, change.Change.Before, opts), nodePoolOptions(plan, change.Change.After, opts)
+		}
+		if cost.Before, err = e.priceState(ctx, change, change.Change.Before, beforeOpts); err == nil {
+			cost.After, err = e.priceState(ctx, change, change.Change.After, afterOpts)
+		}
+		var unsupported *UnsupportedError
+		if errors.As(err, &unsupported) {
+			cost.Err = err
+		} else if err != nil {
+			return costs, err
+		}
+		for _, line := range cost.Before {
+			cost.BeforeMonthly += line.Monthly
+			if line.Err != nil && cost.Err == nil {
+				cost.Err = line.Err
+			}
+		}
+		for _, line := range cost.After {
+			cost.AfterMonthly += line.Monthly
+			if line.Err != nil && cost.Err == nil {
+				cost.Err = line.Err
+			}
+		}
+		costs = append(costs, cost)
+	}
+	return costs, nil
+}
+
+// nodePoolOptions returns opts with the region of the cluster of a node
+// pool, which has no location of its own: the cluster with its
+// kubernetes_cluster_id, or the only region of the clusters of the plan when
+// the id is not known yet. Otherwise opts.Region, from --region, is kept.
+func nodePoolOptions(plan *Plan, values map[string]any, opts TerraformOptions) TerraformOptions {
+	clusterID := stringValue(values, "kubernetes_cluster_id")
+	regions := map[string]bool{}
+	for _, change := range plan.ResourceChanges {
+		if change.Type != "azurerm_kubernetes_cluster" {
+			continue
+		}
+		for _, state := range []map[string]any{change.Change.Before, change.Change.After} {
+			region := NormalizeRegion(stringValue(state, "location"))
+			if region == "" {
+				continue
+			}
+			if clusterID != "" && strings.EqualFold(stringValue(state, "id"), clusterID) {
+				opts.Region = region
+				return opts
+			}
+			regions[region] = true
+		}
+	}
+	if len(regions) == 1 {
+		for region := range regions {
+			opts.Region = region
+		}
+	}
+	return opts
+}
+
+func (e *Estimator) priceState(ctx context.Context, change ResourceChange, values map[string]any, opts TerraformOptions) ([]Line, error) {
+	if values == nil {
+		return nil, nil
+	}
+	resources, err := TerraformResources(change.Address, change.Type, values, opts)
+	if err != nil {
+		return nil, err
+	}
+	lines := make([]Line, 0, len(resources))
+	for _, r := range resources {
+		line, err := e.Price(ctx, r)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+// UnsupportedError reports a resource that cannot be mapped to prices.
+type UnsupportedError struct {
+	Type   string
+	Reason string
+}
+
+func (e *UnsupportedError) Error() string {
+	if e.Reason != "" {
+		return fmt.Sprintf("%s: %s", e.Type, e.Reason)
+	}
+	return fmt.Sprintf("%s is not supported", e.Type)
+}
+
+// TerraformResources maps the state of an azurerm resource to estimate lines.
+// Resources that are free, like resource groups, map to no line.
+func TerraformResources(address, resourceType string, values map[string]any, opts TerraformOptions) ([]Resource, error) {
+	region := NormalizeRegion(stringValue(values, "location"))
+	if region == "" {
+		region = opts.Region
+	}
+	switch resourceType {
+	case "azurerm_linux_virtual_machine", "azurerm_windows_virtual_machine":
+		windows := resourceType == "azurerm_windows_virtual_machine"
+		resources := []Resource{VirtualMachine(address, stringValue(values, "size"), region, windows, 1)}
+		if disks := listValue(values, "os_disk"); len(disks) > 0 {
+			// Images default to a 30 GB Linux or a 127 GB Windows OS disk.
+			size := 30.0
+			if windows {
+				size = 127
+			}
+			disk, err := ManagedDisk(address+".os_disk", stringValue(disks[0], "storage_account_type"), numberValue(disks[0], "disk_size_gb", size), region)
+			if err != nil {
+				return nil, &UnsupportedError{Type: resourceType, Reason: err.Error()}
+			}
+			resources = append(resources, disk)
+		}
+		return resources, nil
+	case "azurerm_virtual_machine":
+		windows := len(listValue(values, "os_profile_windows_config")) > 0
+		return []Resource{VirtualMachine(address, stringValue(values, "vm_size"), region, windows, 1)}, nil
+	case "azurerm_linux_virtual_machine_scale_set", "azurerm_windows_virtual_machine_scale_set":
+		windows := resourceType == "azurerm_windows_virtual_machine_scale_set"
+		return []Resource{VirtualMachine(address, stringValue(values, "sku"), region, windows, numberValue(values, "instances", 1))}, nil
+	case "azurerm_kubernetes_cluster":
+		pools := listValue(values, "default_node_pool")
+		if len(pools) == 0 {
+			return nil, &UnsupportedError{Type: resourceType, Reason: "no default_node_pool in plan"}
+		}
+		return []Resource{nodePool(address+".default_node_pool", pools[0], region)}, nil
+	case "azurerm_kubernetes_cluster_node_pool":
+		return []Resource{nodePool(address, values, region)}, nil
+	case "azurerm_managed_disk":
+		// The size is unknown until apply when copied from an image or a snapshot.
+		size, ok := values["disk_size_gb"].(float64)
+		if !ok {
+			return nil, &UnsupportedError{Type: resourceType, Reason: "disk_size_gb is not known, set it to price the disk"}
+		}
+		disk, err := ManagedDisk(address, stringValue(values, "storage_account_type"), size, region)
+		if err != nil {
+			return nil, &UnsupportedError{Type: resourceType, Reason: err.Error()}
+		}
+		return []Resource{disk}, nil
+	case "azurerm_public_ip":
+		return []Resource{PublicIP(address, stringValue(values, "sku"), stringValue(values, "allocation_method"), region)}, nil
+	case "azurerm_storage_account":
+		if tier := stringValue(values, "account_tier"); strings.EqualFold(tier, "Premium") {
+			return nil, &UnsupportedError{Type: resourceType, Reason: "premium storage accounts are not supported"}
+		}
+		return []Resource{BlobStorage(address, stringValue(values, "access_tier"), stringValue(values, "account_replication_type"), opts.StorageGB, region)}, nil
+	case "azurerm_resource_group", "azurerm_virtual_network", "azurerm_subnet", "azurerm_network_interface",
+		"azurerm_network_security_group", "azurerm_network_security_rule", "azurerm_subnet_network_security_group_association",
+		"azurerm_role_assignment", "azurerm_user_assigned_identity":
+		return nil, nil
+	}
+	return nil, &UnsupportedError{Type: resourceType}
+}
+
+// nodePool prices the VMs of an AKS node pool, using the autoscaler minimum
+// when no node count is planned.
+func nodePool(address string, pool map[string]any, region string) Resource {
+	count := numberValue(pool, "node_count", numberValue(pool, "min_count", 1))
+	windows := strings.EqualFold(stringValue(pool, "os_type"), "Windows")
+	r := VirtualMachine(address, stringValue(pool, "vm_size"), region, windows, count)
+	if strings.EqualFold(stringValue(pool, "priority"), "Spot") {
+		r.Meter = "Spot"
+	}
+	return r
+}
+
+func stringValue(values map[string]any, key string) string {
+	s, _ := values[key].(string)
+	return s
+}
+
+func numberValue(values map[string]any, key string, fallback float64) float64 {
+	if n, ok := values[key].(float64); ok {
+		return n
+	}
+	return fallback
+}
+
+func listValue(values map[string]any, key string) []map[string]any {
+	list, _ := values[key].([]any)
+	var maps []map[string]any
+	for _, v := range list {
+		if m, ok := v.(map[string]any); ok {
+			maps = append(maps, m)
+		}
+	}
+	return maps
+}
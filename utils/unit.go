@@ -0,0 +1,159 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Dimension is what a unit of measure counts.
+type Dimension int
+
+const (
+	// Count units price a number of operations, events, transactions...
+	Count Dimension = iota
+	// Time units price a resource running for a duration.
+	Time
+	// Data units price an amount of data processed or transferred.
+	Data
+	// DataTime units price an amount of data held for a duration, like storage.
+	DataTime
+)
+
+func (d Dimension) String() string {
+	switch d {
+	case Time:
+		return "time"
+	case Data:
+		return "data"
+	case DataTime:
+		return "data-over-time"
+	}
+	return "count"
+}
+
+// Unit is a parsed unit of measure such as "100 Hours", "1 GB/Month" or "10K".
+type Unit struct {
+	Dimension Dimension
+	// Quantity is how many base units one price covers: hours for Time, GB for
+	// Data and DataTime, items for Count.
+	Quantity float64
+	// Period is the number of hours a DataTime price covers.
+	Period float64
+	// Name is the counted thing for Count units, e.g. "Transactions".
+	Name string
+	// Count is the number of items a Time price covers when it is priced per
+	// items and period, e.g. 1e7 for "10 Million/Month", and 0 otherwise.
+	Count float64
+}
+
+var hoursPer = map[string]float64{
+	"second": 1.0 / 3600,
+	"minute": 1.0 / 60,
+	"hour":   1,
+	"day":    24,
+	"month":  HoursPerMonth,
+	"year":   HoursPerMonth * 12,
+}
+
+var gbPer = map[string]float64{
+	"kb":  1.0 / 1024 / 1024,
+	"mb":  1.0 / 1024,
+	"gb":  1,
+	"tb":  1024,
+	"pb":  1024 * 1024,
+	"kib": 1.0 / 1024 / 1024,
+	"mib": 1.0 / 1024,
+	"gib": 1,
+	"tib": 1024,
+	"pib": 1024 * 1024,
+}
+
+var scale = map[string]float64{
+	"k":        1e3,
+	"thousand": 1e3,
+	"m":        1e6,
+	"million":  1e6,
+	"b":        1e9,
+	"billion":  1e9,
+}
+
+// ParseUnit parses the unitOfMeasure of a price item.
+func ParseUnit(s string) (Unit, error) {
+	text, per, _ := strings.Cut(strings.TrimSpace(s), "/")
+	quantity, words, err := parseQuantity(text)
+	if err != nil {
+		return Unit{}, fmt.Errorf("invalid unit of measure %q: %w", s, err)
+	}
+	unit := Unit{Dimension: Count, Quantity: quantity, Name: strings.Join(words, " ")}
+
+	if len(words) > 0 {
+		noun := strings.ToLower(words[0])
+		if hours, ok := timeUnit(noun); ok {
+			unit = Unit{Dimension: Time, Quantity: quantity * hours}
+		} else if gb, ok := gbPer[noun]; ok {
+			unit = Unit{Dimension: Data, Quantity: quantity * gb}
+			// "1 GB Hour" and "1 GiB Second" are data held over time.
+			if len(words) > 1 {
+				if hours, ok := timeUnit(strings.ToLower(words[1])); ok {
+					unit.Dimension, unit.Period = DataTime, hours
+				}
+			}
+		}
+	}
+
+	if per = strings.TrimSpace(per); per != "" {
+		hours, ok := timeUnit(strings.ToLower(per))
+		if !ok {
+			return Unit{}, fmt.Errorf("invalid unit of measure %q: unknown period %q", s, per)
+		}
+		switch unit.Dimension {
+		case Data:
+			unit.Dimension, unit.Period = DataTime, hours
+		case Count:
+			// "1/Month" or "10 Million/Month": a price per items for a
+			// period.
+			unit = Unit{Dimension: Time, Quantity: hours, Count: unit.Quantity}
+		default:
+			return Unit{}, fmt.Errorf("invalid unit of measure %q: unexpected period", s)
+		}
+	}
+	return unit, nil
+}
+
+// parseQuantity splits "10,000 Transactions" or "10K" into a number and the
+// remaining words. A missing number means one.
+func parseQuantity(text string) (float64, []string, error) {
+	end := strings.IndexFunc(text, func(r rune) bool {
+		return !unicode.IsDigit(r) && r != ',' && r != '.'
+	})
+	if end < 0 {
+		end = len(text)
+	}
+	quantity := 1.0
+	if number := strings.ReplaceAll(text[:end], ",", ""); number != "" {
+		var err error
+		if quantity, err = strconv.ParseFloat(number, 64); err != nil {
+			return 0, nil, err
+		}
+		if quantity <= 0 {
+			return 0, nil, fmt.Errorf("quantity must be positive")
+		}
+	}
+	words := strings.Fields(text[end:])
+	// The scale is either glued to the number ("10K") or a word ("1 Million").
+	if len(words) > 0 {
+		if factor, ok := scale[strings.ToLower(words[0])]; ok {
+			quantity *= factor
+			words = words[1:]
+		}
+	}
+	return quantity, words, nil
+}
+
+// timeUnit returns the hours in a singular or plural time noun.
+func timeUnit(noun string) (float64, bool) {
+	hours, ok := hoursPer[strings.TrimSuffix(noun, "s")]
+	return hours, ok
+}
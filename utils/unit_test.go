@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseUnit(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Unit
+	}{
+		{"1 Hour", Unit{Dimension: Time, Quantity: 1}},
+		{"100 Hours", Unit{Dimension: Time, Quantity: 100}},
+		{"1 GB/Month", Unit{Dimension: DataTime, Quantity: 1, Period: HoursPerMonth}},
+		{"1 GB", Unit{Dimension: Data, Quantity: 1}},
+		{"1 GiB Hour", Unit{Dimension: DataTime, Quantity: 1, Period: 1}},
+		{"10K", Unit{Dimension: Count, Quantity: 10000}},
+		{"10,000 Transactions", Unit{Dimension: Count, Quantity: 10000, Name: "Transactions"}},
+		{"1/Month", Unit{Dimension: Time, Quantity: HoursPerMonth, Count: 1}},
+		{"1 User/Month", Unit{Dimension: Time, Quantity: HoursPerMonth, Count: 1}},
+		{"10 Million/Month", Unit{Dimension: Time, Quantity: HoursPerMonth, Count: 1e7}},
+		{"1/Day", Unit{Dimension: Time, Quantity: 24, Count: 1}},
+	}
+	for _, tt := range tests {
+		got, err := ParseUnit(tt.in)
+		if err != nil {
+			t.Errorf("ParseUnit(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseUnit(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseUnitErrors(t *testing.T) {
+	for _, in := range []string{"0 Hours", "1 GB/Fortnight", "1 Hour/Month"} {
+		if _, err := ParseUnit(in); err == nil {
+			t.Errorf("ParseUnit(%q) succeeded, want an error", in)
+		}
+	}
+}
+
+func TestCostOfPerPeriodCounts(t *testing.T) {
+	tests := []struct {
+		unit        string
+		price       float64
+		consumption Consumption
+		want        float64
+	}{
+		// A disk priced per month, held for a month.
+		{"1/Month", 19.71, Consumption{Hours: HoursPerMonth}, 19.71},
+		{"1/Month", 19.71, Consumption{Hours: HoursPerMonth / 2}, 9.855},
+		// 10 million items for a month cost the price of the pack, not 10 million times it.
+		{"10 Million/Month", 5, Consumption{Hours: HoursPerMonth, Count: 1e7}, 5},
+		{"10 Million/Month", 5, Consumption{Hours: HoursPerMonth, Count: 2e7}, 10},
+		{"10 Million/Month", 5, Consumption{Hours: 24, Count: 1e6}, 5.0 * 24 / HoursPerMonth / 10},
+		// Without a count, the pack is billed once.
+		{"10 Million/Month", 5, Consumption{Hours: HoursPerMonth}, 5},
+		{"1/Day", 2, Consumption{Hours: HoursPerMonth}, 2.0 * HoursPerMonth / 24},
+	}
+	for _, tt := range tests {
+		unit, err := ParseUnit(tt.unit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := Cost(unit, tt.price, tt.consumption); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%+v of %q at %g cost %g, want %g", tt.consumption, tt.unit, tt.price, got, tt.want)
+		}
+	}
+}
+
+func TestMonthlyPrice(t *testing.T) {
+	tests := []struct {
+		unit  string
+		price float64
+		count float64
+		want  float64
+		ok    bool
+	}{
+		{"1 Hour", 0.1, 0, 73, true},
+		{"100 Hours", 10, 0, 73, true},
+		{"1/Month", 19.71, 0, 19.71, true},
+		{"10 Million/Month", 5, 0, 5, true},
+		{"10 Million/Month", 5, 3e7, 15, true},
+		{"1 GB", 0.08, 0, 0, false},
+		{"10K", 0.05, 0, 0, false},
+	}
+	for _, tt := range tests {
+		unit, err := ParseUnit(tt.unit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, ok := MonthlyPrice(unit, tt.price, tt.count)
+		if ok != tt.ok || math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("MonthlyPrice(%q, %g, %g) = %g, %v, want %g, %v", tt.unit, tt.price, tt.count, got, ok, tt.want, tt.ok)
+		}
+	}
+}
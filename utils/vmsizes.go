@@ -0,0 +1,230 @@
+package utils
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The VM features a size can be required to have.
+const (
+	FeaturePremiumIO             = "premiumio"
+	FeatureAcceleratedNetworking = "acceleratednetworking"
+	FeatureEphemeralOSDisk       = "ephemeralosdisk"
+	FeatureEncryptionAtHost      = "encryptionathost"
+	FeatureGen2                  = "gen2"
+	FeatureGPU                   = "gpu"
+	FeatureArm64                 = "arm64"
+)
+
+// VMFeatures lists the known features.
+var VMFeatures = []string{FeaturePremiumIO, FeatureAcceleratedNetworking, FeatureEphemeralOSDisk, FeatureEncryptionAtHost, FeatureGen2, FeatureGPU, FeatureArm64}
+
+// The capacities VM prices can be normalized by.
+const (
+	PerVCPU = "vcpu"
+	PerGiB  = "gib"
+	PerACU  = "acu"
+)
+
+// PerUnits lists the capacities VM prices can be normalized by.
+var PerUnits = []string{PerVCPU, PerGiB, PerACU}
+
+// VMSize is the capabilities of a VM size.
+type VMSize struct {
+	Name         string   `json:"name"`
+	Family       string   `json:"family"`
+	VCPUs        int      `json:"vCPUs"`
+	MemoryGiB    float64  `json:"memoryGiB"`
+	ACUs         int      `json:"acus,omitempty"`
+	GPUs         int      `json:"gpus,omitempty"`
+	MaxDataDisks int      `json:"maxDataDisks,omitempty"`
+	Features     []string `json:"features"`
+	// Locations are the regions offering the size, when known.
+	Locations []string `json:"locations,omitempty"`
+}
+
+// Has reports whether the size has feature.
+func (s VMSize) Has(feature string) bool {
+	for _, f := range s.Features {
+		if strings.EqualFold(f, feature) {
+			return true
+		}
+	}
+	return false
+}
+
+// Capacity returns the vCPUs, the GiB of memory or the ACUs of all the vCPUs
+// of the size for per, one of PerUnits. It reports false when unknown.
+func (s VMSize) Capacity(per string) (float64, bool) {
+	var capacity float64
+	switch per {
+	case PerVCPU:
+		capacity = float64(s.VCPUs)
+	case PerGiB:
+		capacity = s.MemoryGiB
+	case PerACU:
+		capacity = float64(s.ACUs * s.VCPUs)
+	}
+	return capacity, capacity > 0
+}
+
+// OfferedIn reports whether the size is offered in region, assuming it is
+// when its locations are not known.
+func (s VMSize) OfferedIn(region string) bool {
+	if len(s.Locations) == 0 {
+		return true
+	}
+	for _, l := range s.Locations {
+		if NormalizeRegion(l) == NormalizeRegion(region) {
+			return true
+		}
+	}
+	return false
+}
+
+// VMCatalog is a list of VM sizes.
+type VMCatalog struct {
+	Sizes []VMSize `json:"sizes"`
+}
+
+//go:embed vmsizes.json
+var bundledVMSizes []byte
+
+// DefaultVMCatalogPath returns where an imported catalog replacing the bundled
+// one is kept.
+func DefaultVMCatalogPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "cloudcost", "vmsizes.json"), nil
+}
+
+// LoadVMCatalog reads the catalog at path, or the bundled catalog when there
+// is no file at path.
+func LoadVMCatalog(path string) (*VMCatalog, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		data = bundledVMSizes
+	} else if err != nil {
+		return nil, err
+	}
+	var catalog VMCatalog
+	if err := json.Unmarshal(data, &catalog); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &catalog, nil
+}
+
+// Lookup returns the size called name, ignoring case.
+func (c *VMCatalog) Lookup(name string) (VMSize, bool) {
+	for _, s := range c.Sizes {
+		if strings.EqualFold(s.Name, name) {
+			return s, true
+		}
+	}
+	return VMSize{}, false
+}
+
+// Save writes the catalog to path, creating its directory.
+func (c *VMCatalog) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// listSku is a resource SKU as listed by 'az vm list-skus -o json'.
+type listSku struct {
+	ResourceType string   `json:"resourceType"`
+	Name         string   `json:"name"`
+	Family       string   `json:"family"`
+	Locations    []string `json:"locations"`
+	Capabilities []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	} `json:"capabilities"`
+}
+
+// ImportVMSkus builds a catalog from the JSON output of 'az vm list-skus'.
+// SKUs listed once per region are merged.
+func ImportVMSkus(r io.Reader) (*VMCatalog, error) {
+	var skus []listSku
+	if err := json.NewDecoder(r).Decode(&skus); err != nil {
+		return nil, fmt.Errorf("reading az vm list-skus output: %w", err)
+	}
+	catalog := &VMCatalog{}
+	index := map[string]int{}
+	for _, sku := range skus {
+		if !strings.EqualFold(sku.ResourceType, "virtualMachines") {
+			continue
+		}
+		if i, ok := index[sku.Name]; ok {
+			for _, l := range sku.Locations {
+				if !catalog.Sizes[i].OfferedIn(l) {
+					catalog.Sizes[i].Locations = append(catalog.Sizes[i].Locations, strings.ToLower(l))
+				}
+			}
+			continue
+		}
+		size := VMSize{Name: sku.Name, Family: sku.Family, Features: []string{}}
+		for _, l := range sku.Locations {
+			size.Locations = append(size.Locations, strings.ToLower(l))
+		}
+		for _, c := range sku.Capabilities {
+			number, _ := strconv.ParseFloat(c.Value, 64)
+			switch c.Name {
+			case "vCPUs":
+				size.VCPUs = int(number)
+			case "MemoryGB":
+				size.MemoryGiB = number
+			case "ACUs":
+				size.ACUs = int(number)
+			case "GPUs":
+				size.GPUs = int(number)
+			case "MaxDataDiskCount":
+				size.MaxDataDisks = int(number)
+			case "PremiumIO":
+				size.addFeature(FeaturePremiumIO, c.Value == "True")
+			case "AcceleratedNetworkingEnabled":
+				size.addFeature(FeatureAcceleratedNetworking, c.Value == "True")
+			case "EphemeralOSDiskSupported":
+				size.addFeature(FeatureEphemeralOSDisk, c.Value == "True")
+			case "EncryptionAtHostSupported":
+				size.addFeature(FeatureEncryptionAtHost, c.Value == "True")
+			case "HyperVGenerations":
+				size.addFeature(FeatureGen2, strings.Contains(c.Value, "V2"))
+			case "CpuArchitectureType":
+				size.addFeature(FeatureArm64, strings.EqualFold(c.Value, "Arm64"))
+			}
+		}
+		size.addFeature(FeatureGPU, size.GPUs > 0)
+		index[sku.Name] = len(catalog.Sizes)
+		catalog.Sizes = append(catalog.Sizes, size)
+	}
+	if len(catalog.Sizes) == 0 {
+		return nil, errors.New("no virtual machine size found in the az vm list-skus output")
+	}
+	for i := range catalog.Sizes {
+		sort.Strings(catalog.Sizes[i].Features)
+	}
+	return catalog, nil
+}
+
+func (s *VMSize) addFeature(feature string, has bool) {
+	if has && !s.Has(feature) {
+		s.Features = append(s.Features, feature)
+	}
+}